@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const maxBatchItems = 100
+
+// batchPool bounds number of extractions running for all batch requests together,
+// sized by cfg.BatchWorkers
+var batchPool = make(chan struct{}, cfg.BatchWorkers)
+
+// initBatchPool resize pool after config is loaded, call it before serving requests
+func initBatchPool() {
+	batchPool = make(chan struct{}, cfg.BatchWorkers)
+}
+
+// BatchItem single url to extract, might be sent as plain string or object
+type BatchItem struct {
+	URL string `json:"url"`
+	ExtractOptions
+}
+
+// UnmarshalJSON accept "https://..." as well as {"url": "https://..."}
+func (b *BatchItem) UnmarshalJSON(data []byte) error {
+	var url string
+	if err := json.Unmarshal(data, &url); err == nil {
+		b.URL = url
+		return nil
+	}
+
+	type item BatchItem
+	var i item
+	if err := json.Unmarshal(data, &i); err != nil {
+		return err
+	}
+	*b = BatchItem(i)
+	return nil
+}
+
+// BatchResult result of single batch item
+type BatchResult struct {
+	Index   int     `json:"index"`
+	URL     string  `json:"url"`
+	Success bool    `json:"success"`
+	Status  int     `json:"status"`
+	Error   string  `json:"error,omitempty"`
+	Result  *Output `json:"result,omitempty"`
+}
+
+// handleBatch extract many urls at once, with ?stream=1 or Accept: application/x-ndjson
+// results are written as NDJSON in order of completion
+func handleBatch(w http.ResponseWriter, r *http.Request) {
+	setCORS(w)
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST, OPTIONS")
+		rejectExtraction(w, "batch", http.StatusMethodNotAllowed, "method", "Use POST with JSON array of urls")
+		return
+	}
+
+	var items []BatchItem
+	if err := json.NewDecoder(io.LimitReader(r.Body, cfg.MaxHTMLSize)).Decode(&items); err != nil {
+		rejectExtraction(w, "batch", http.StatusBadRequest, "invalid_json", fmt.Sprintf("Failed to decode JSON: %v", err))
+		return
+	}
+
+	if len(items) == 0 || len(items) > maxBatchItems {
+		rejectExtraction(w, "batch", http.StatusBadRequest, "batch_size", fmt.Sprintf("Batch needs between 1 and %d urls", maxBatchItems))
+		return
+	}
+
+	results := make(chan BatchResult, len(items))
+	var wg sync.WaitGroup
+	for i, item := range items {
+		wg.Add(1)
+		go func(i int, item BatchItem) {
+			defer wg.Done()
+			results <- extractBatchItem(i, item, r)
+		}(i, item)
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	stream := r.URL.Query().Get("stream") == "1" || r.URL.Query().Get("stream") == "true" ||
+		strings.Contains(r.Header.Get("Accept"), "application/x-ndjson")
+
+	if stream {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+		flusher, _ := w.(http.Flusher)
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		for result := range results {
+			if err := enc.Encode(result); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		return
+	}
+
+	// keep order of request
+	ordered := make([]BatchResult, len(items))
+	for result := range results {
+		ordered[result.Index] = result
+	}
+
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(ordered); err != nil {
+		return
+	}
+}
+
+// extractBatchItem wait for free slot in pool and extract single url
+func extractBatchItem(i int, item BatchItem, r *http.Request) BatchResult {
+	result := BatchResult{
+		Index: i,
+		URL:   item.URL,
+	}
+
+	if item.URL == "" {
+		observeRejection("batch", http.StatusBadRequest, "missing_url")
+		result.Status = http.StatusBadRequest
+		result.Error = "Can't work without url"
+		return result
+	}
+	if err := item.ExtractOptions.validate(); err != nil {
+		observeRejection("batch", http.StatusBadRequest, "invalid_options")
+		result.Status = http.StatusBadRequest
+		result.Error = err.Error()
+		return result
+	}
+
+	// caller gone while all slots are busy
+	select {
+	case batchPool <- struct{}{}:
+		defer func() { <-batchPool }()
+	case <-r.Context().Done():
+		observeExtraction("batch", http.StatusServiceUnavailable)
+		result.Status = http.StatusServiceUnavailable
+		result.Error = fmt.Sprintf("Request cancelled while waiting for worker: %v", r.Context().Err())
+		return result
+	}
+
+	output, status := extractURL(item.URL, item.ExtractOptions, r)
+	observeExtraction("batch", status)
+	result.Success = output.Success
+	result.Status = status
+	if !output.Success {
+		result.Error = output.Message
+		return result
+	}
+	result.Result = &output
+	return result
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newPageServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `<html><head><title>%s</title>
+<meta property="og:image" content="https://example.com/lead.jpg">
+</head><body><p>content</p></body></html>`, r.URL.Path)
+	}))
+}
+
+func TestHandleBatch(t *testing.T) {
+	ts := newPageServer()
+	defer ts.Close()
+
+	payload := fmt.Sprintf(`["%s/a", {"url": "%s/b"}, {"url": ""}, "://broken"]`, ts.URL, ts.URL)
+	req, err := http.NewRequest("POST", "/batch/", strings.NewReader(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(handleBatch)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+
+	var results []BatchResult
+	if err := json.Unmarshal(rr.Body.Bytes(), &results); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 4 {
+		t.Fatalf("handler returned %d results, want 4", len(results))
+	}
+	for i, title := range []string{"/a", "/b"} {
+		if !results[i].Success || results[i].Result == nil || results[i].Result.Title != title {
+			t.Errorf("result %d unexpected: %+v", i, results[i])
+		}
+	}
+	for _, i := range []int{2, 3} {
+		if results[i].Success || results[i].Error == "" {
+			t.Errorf("result %d should fail: %+v", i, results[i])
+		}
+	}
+}
+
+func TestHandleBatchStream(t *testing.T) {
+	ts := newPageServer()
+	defer ts.Close()
+
+	payload := fmt.Sprintf(`["%s/a", "%s/b", "%s/c"]`, ts.URL, ts.URL, ts.URL)
+	req, err := http.NewRequest("POST", "/batch/?stream=1", strings.NewReader(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(handleBatch)
+	handler.ServeHTTP(rr, req)
+
+	if ct := rr.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("handler returned wrong content type: got %v", ct)
+	}
+
+	seen := make(map[int]bool)
+	scanner := bufio.NewScanner(rr.Body)
+	for scanner.Scan() {
+		var result BatchResult
+		if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+			t.Fatal(err)
+		}
+		seen[result.Index] = result.Success
+	}
+
+	if len(seen) != 3 || !seen[0] || !seen[1] || !seen[2] {
+		t.Errorf("handler streamed unexpected results: %v", seen)
+	}
+}
+
+func TestHandleBatchEmpty(t *testing.T) {
+	req, err := http.NewRequest("POST", "/batch/", strings.NewReader(`[]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(handleBatch)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusBadRequest)
+	}
+}
+
+func TestExtractBatchItemCancelled(t *testing.T) {
+	// all slots busy
+	for i := 0; i < cap(batchPool); i++ {
+		batchPool <- struct{}{}
+	}
+	defer func() {
+		for i := 0; i < cap(batchPool); i++ {
+			<-batchPool
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest("POST", "/batch/", nil).WithContext(ctx)
+
+	result := extractBatchItem(0, BatchItem{URL: "https://example.com/"}, req)
+	if result.Success || result.Status != http.StatusServiceUnavailable {
+		t.Errorf("extractBatchItem returned %+v, want %d", result, http.StatusServiceUnavailable)
+	}
+}
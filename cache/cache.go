@@ -0,0 +1,285 @@
+package cache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Entry cached extraction result with validators of origin response
+type Entry struct {
+	Key          string    `json:"key"`
+	Value        []byte    `json:"value"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	StoredAt     time.Time `json:"stored_at"`
+}
+
+// Fresh entry is younger than ttl
+func (e Entry) Fresh(ttl time.Duration) bool {
+	return time.Since(e.StoredAt) < ttl
+}
+
+// Revalidatable entry has validators to send with conditional request
+func (e Entry) Revalidatable() bool {
+	return e.ETag != "" || e.LastModified != ""
+}
+
+// Store cache backend, implementations must be safe for concurrent use
+type Store interface {
+	Get(key string) (Entry, bool)
+	Set(entry Entry) error
+}
+
+// NormalizeURL build cache key, lower case scheme and host, drop default port,
+// fragment and sort query parameters
+func NormalizeURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return raw
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	if port != "" {
+		host = host + ":" + port
+	}
+	u.Host = host
+	u.Fragment = ""
+	u.RawFragment = ""
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	// Encode sorts by key
+	u.RawQuery = u.Query().Encode()
+
+	return u.String()
+}
+
+// Memory LRU store limited by number of entries and total size of values
+type Memory struct {
+	mu         sync.Mutex
+	maxEntries int
+	maxBytes   int
+	size       int
+	ll         *list.List
+	items      map[string]*list.Element
+}
+
+// NewMemory create LRU store, zero limit means no limit
+func NewMemory(maxEntries, maxBytes int) *Memory {
+	return &Memory{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+	}
+}
+
+// Get entry and mark it as recently used
+func (m *Memory) Get(key string) (Entry, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	el, ok := m.items[key]
+	if !ok {
+		return Entry{}, false
+	}
+	m.ll.MoveToFront(el)
+	return el.Value.(Entry), true
+}
+
+// Set store entry, evict least recently used ones over limits
+func (m *Memory) Set(entry Entry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if el, ok := m.items[entry.Key]; ok {
+		m.size -= len(el.Value.(Entry).Value)
+		el.Value = entry
+		m.ll.MoveToFront(el)
+	} else {
+		m.items[entry.Key] = m.ll.PushFront(entry)
+	}
+	m.size += len(entry.Value)
+
+	for m.ll.Len() > 0 && ((m.maxEntries > 0 && m.ll.Len() > m.maxEntries) || (m.maxBytes > 0 && m.size > m.maxBytes)) {
+		el := m.ll.Back()
+		old := el.Value.(Entry)
+		m.ll.Remove(el)
+		delete(m.items, old.Key)
+		m.size -= len(old.Value)
+	}
+	return nil
+}
+
+// Len number of entries
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.ll.Len()
+}
+
+// Disk store keeping one JSON file per entry, survives restarts. Limited like Memory,
+// files stored first are removed first
+type Disk struct {
+	mu         sync.Mutex
+	dir        string
+	maxEntries int
+	maxBytes   int64
+	entries    int
+	size       int64
+}
+
+// NewDisk create store in dir, directory is created when missing, zero limit means no limit
+func NewDisk(dir string, maxEntries, maxBytes int) (*Disk, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	d := &Disk{dir: dir, maxEntries: maxEntries, maxBytes: int64(maxBytes)}
+	files, err := d.files()
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		d.entries++
+		d.size += f.Size()
+	}
+	return d, nil
+}
+
+func (d *Disk) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(d.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// files entries stored in directory, oldest first
+func (d *Disk) files() ([]os.FileInfo, error) {
+	dirEntries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]os.FileInfo, 0, len(dirEntries))
+	for _, e := range dirEntries {
+		if !e.Type().IsRegular() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		// removed in the meantime
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, info)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].ModTime().Before(files[j].ModTime()) })
+	return files, nil
+}
+
+// Get read entry from disk
+func (d *Disk) Get(key string) (Entry, bool) {
+	data, err := os.ReadFile(d.path(key))
+	if err != nil {
+		return Entry{}, false
+	}
+
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Key != key {
+		return Entry{}, false
+	}
+	return entry, true
+}
+
+// Set write entry, file is renamed in place so readers never see partial entry,
+// oldest entries over limits are removed
+func (d *Disk) Set(entry Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(d.dir, "tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	path := d.path(entry.Key)
+	if old, err := os.Stat(path); err == nil {
+		d.entries--
+		d.size -= old.Size()
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	d.entries++
+	d.size += int64(len(data))
+
+	if !d.over() {
+		return nil
+	}
+	return d.evict()
+}
+
+// over store exceeds one of limits
+func (d *Disk) over() bool {
+	return d.entries > 0 && ((d.maxEntries > 0 && d.entries > d.maxEntries) || (d.maxBytes > 0 && d.size > d.maxBytes))
+}
+
+// evict remove oldest files until store is within limits, counters are rebuilt from
+// directory, so files added or removed by other processes are taken into account
+func (d *Disk) evict() error {
+	files, err := d.files()
+	if err != nil {
+		return err
+	}
+
+	d.entries, d.size = 0, 0
+	for _, f := range files {
+		d.entries++
+		d.size += f.Size()
+	}
+	for _, f := range files {
+		if !d.over() {
+			break
+		}
+		if err := os.Remove(filepath.Join(d.dir, f.Name())); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		d.entries--
+		d.size -= f.Size()
+	}
+	return nil
+}
+
+// Len number of entries
+func (d *Disk) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.entries
+}
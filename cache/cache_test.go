@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		src, expected string
+	}{
+		{"HTTP://Example.COM:80", "http://example.com/"},
+		{"https://example.com:443/a?b=2&a=1#top", "https://example.com/a?a=1&b=2"},
+		{"https://example.com:8443/a", "https://example.com:8443/a"},
+	}
+
+	for _, tt := range tests {
+		if result := NormalizeURL(tt.src); result != tt.expected {
+			t.Errorf("NormalizeURL(%q) = %q, want %q", tt.src, result, tt.expected)
+		}
+	}
+}
+
+func TestMemoryEviction(t *testing.T) {
+	m := NewMemory(2, 0)
+	m.Set(Entry{Key: "a", Value: []byte("1")})
+	m.Set(Entry{Key: "b", Value: []byte("2")})
+
+	// touch a, so b is least recently used
+	m.Get("a")
+	m.Set(Entry{Key: "c", Value: []byte("3")})
+
+	if _, ok := m.Get("b"); ok {
+		t.Errorf("Memory kept least recently used entry")
+	}
+	if _, ok := m.Get("a"); !ok {
+		t.Errorf("Memory evicted recently used entry")
+	}
+
+	m = NewMemory(0, 5)
+	m.Set(Entry{Key: "a", Value: []byte("123")})
+	m.Set(Entry{Key: "b", Value: []byte("456")})
+	if m.Len() != 1 {
+		t.Errorf("Memory has %d entries, want 1 with byte limit", m.Len())
+	}
+}
+
+func TestDisk(t *testing.T) {
+	dir := t.TempDir()
+	d, err := NewDisk(dir, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry := Entry{Key: "https://example.com/", Value: []byte(`{"title":"x"}`), ETag: `"abc"`, StoredAt: time.Now()}
+	if err := d.Set(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	// new store on same directory, like after restart
+	d, err = NewDisk(dir, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, ok := d.Get(entry.Key)
+	if !ok || string(result.Value) != string(entry.Value) || result.ETag != entry.ETag {
+		t.Errorf("Disk.Get = %+v, %v", result, ok)
+	}
+	if !result.Fresh(time.Minute) || result.Fresh(0) {
+		t.Errorf("Entry.Fresh returned wrong value")
+	}
+	if !result.Revalidatable() || (Entry{Key: entry.Key}).Revalidatable() {
+		t.Errorf("Entry.Revalidatable returned wrong value")
+	}
+}
+
+func TestDiskEviction(t *testing.T) {
+	dir := t.TempDir()
+	d, err := NewDisk(dir, 2, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old := time.Now().Add(-time.Hour)
+	for i, key := range []string{"a", "b"} {
+		if err := d.Set(Entry{Key: key, Value: []byte("123")}); err != nil {
+			t.Fatal(err)
+		}
+		// mtime granularity of some file systems is too coarse to order entries
+		stamp := old.Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(d.path(key), stamp, stamp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := d.Set(Entry{Key: "c", Value: []byte("123")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := d.Get("a"); ok {
+		t.Errorf("Disk kept oldest entry over entry limit")
+	}
+	if _, ok := d.Get("c"); !ok || d.Len() != 2 {
+		t.Errorf("Disk has %d entries, want 2 with newest one", d.Len())
+	}
+
+	// limits apply to entries found after restart
+	d, err = NewDisk(dir, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Len() != 2 {
+		t.Errorf("NewDisk found %d entries, want 2", d.Len())
+	}
+	d.Set(Entry{Key: "d", Value: []byte("456")})
+	if d.Len() != 0 {
+		t.Errorf("Disk has %d entries, want 0 with byte limit", d.Len())
+	}
+}
@@ -0,0 +1,384 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Duration time.Duration read from and written as "10s" strings
+type Duration time.Duration
+
+// MarshalJSON write duration as string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON read "10s" or number of nanoseconds
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		v, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		*d = Duration(v)
+		return nil
+	}
+
+	var n int64
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("invalid duration %s", data)
+	}
+	*d = Duration(n)
+	return nil
+}
+
+// UnmarshalYAML read "10s"
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	v, err := time.ParseDuration(value.Value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Cache settings of extraction result cache
+type Cache struct {
+	Backend  string   `json:"backend" yaml:"backend"`
+	TTL      Duration `json:"ttl" yaml:"ttl"`
+	Size     int      `json:"size" yaml:"size"`
+	MaxBytes int      `json:"max_bytes" yaml:"max_bytes"`
+	Dir      string   `json:"dir" yaml:"dir"`
+}
+
+// Scoring weights of lead image candidates, see score.go
+type Scoring struct {
+	MinWidth         int      `json:"min_width" yaml:"min_width"`
+	MinHeight        int      `json:"min_height" yaml:"min_height"`
+	MinAspect        float64  `json:"min_aspect" yaml:"min_aspect"`
+	MaxAspect        float64  `json:"max_aspect" yaml:"max_aspect"`
+	AreaTarget       int      `json:"area_target" yaml:"area_target"`
+	AreaWeight       float64  `json:"area_weight" yaml:"area_weight"`
+	PositionWeight   float64  `json:"position_weight" yaml:"position_weight"`
+	MetaWeight       float64  `json:"meta_weight" yaml:"meta_weight"`
+	ContentWeight    float64  `json:"content_weight" yaml:"content_weight"`
+	AltWeight        float64  `json:"alt_weight" yaml:"alt_weight"`
+	AspectPenalty    float64  `json:"aspect_penalty" yaml:"aspect_penalty"`
+	HintPenalty      float64  `json:"hint_penalty" yaml:"hint_penalty"`
+	NegativeHints    []string `json:"negative_hints" yaml:"negative_hints"`
+	VectorScalable   bool     `json:"vector_scalable" yaml:"vector_scalable"` // SVG with known aspect ratio counts as big enough
+	AnimationPenalty float64  `json:"animation_penalty" yaml:"animation_penalty"`
+	SkipAnimated     bool     `json:"skip_animated" yaml:"skip_animated"` // animated images are never picked
+}
+
+// SSRF allow and deny lists of IPs, CIDRs, host names or *.domain wildcards
+type SSRF struct {
+	Allow []string `json:"allow" yaml:"allow"`
+	Deny  []string `json:"deny" yaml:"deny"`
+}
+
+// Config of prom service, secrets (PROXY_OWN, WEBHOOK_SECRET) stay in environment
+// so config can be shown on /status
+type Config struct {
+	Listen         string   `json:"listen" yaml:"listen"`
+	TLSVerify      bool     `json:"tls_verify" yaml:"tls_verify"`
+	CABundle       string   `json:"ca_bundle" yaml:"ca_bundle"`
+	FetchTimeout   Duration `json:"fetch_timeout" yaml:"fetch_timeout"`
+	ImageTimeout   Duration `json:"image_timeout" yaml:"image_timeout"`
+	PageUserAgent  string   `json:"page_user_agent" yaml:"page_user_agent"`
+	ImageUserAgent string   `json:"image_user_agent" yaml:"image_user_agent"`
+	Workers        int      `json:"workers" yaml:"workers"`
+	BatchWorkers   int      `json:"batch_workers" yaml:"batch_workers"`
+	MaxBodySize    int64    `json:"max_body_size" yaml:"max_body_size"`
+	MaxImageBytes  int64    `json:"max_image_bytes" yaml:"max_image_bytes"`
+	MaxHTMLSize    int64    `json:"max_html_size" yaml:"max_html_size"`
+	ShutdownGrace  Duration `json:"shutdown_grace" yaml:"shutdown_grace"`
+	Cache          Cache    `json:"cache" yaml:"cache"`
+	Scoring        Scoring  `json:"scoring" yaml:"scoring"`
+	SSRF           SSRF     `json:"ssrf" yaml:"ssrf"`
+}
+
+// Default config, same values prom always used
+func Default() Config {
+	return Config{
+		Listen:         ":9999",
+		TLSVerify:      false,
+		FetchTimeout:   Duration(10 * time.Second),
+		ImageTimeout:   Duration(10 * time.Second),
+		PageUserAgent:  "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+		ImageUserAgent: "Googlebot-Image/1.0",
+		Workers:        5,
+		BatchWorkers:   20,
+		MaxBodySize:    10 << 20,
+		MaxImageBytes:  51200,
+		MaxHTMLSize:    10 << 20,
+		ShutdownGrace:  Duration(30 * time.Second),
+		Cache: Cache{
+			TTL:      Duration(15 * time.Minute),
+			Size:     1000,
+			MaxBytes: 64 << 20,
+			Dir:      "/tmp/prom-cache",
+		},
+		Scoring: Scoring{
+			MinWidth:         100,
+			MinHeight:        100,
+			MinAspect:        0.33,
+			MaxAspect:        3,
+			AreaTarget:       300000,
+			AreaWeight:       50,
+			PositionWeight:   15,
+			MetaWeight:       30,
+			ContentWeight:    25,
+			AltWeight:        5,
+			AspectPenalty:    40,
+			HintPenalty:      40,
+			AnimationPenalty: 20,
+			NegativeHints:    []string{"logo", "avatar", "icon", "ad", "ads", "advert", "banner", "pixel", "sprite", "spacer", "tracking", "badge", "emoji", "button"},
+		},
+		SSRF: SSRF{
+			Allow: []string{},
+			Deny:  []string{},
+		},
+	}
+}
+
+// option single setting available as flag and environment variable
+type option struct {
+	flag, env, usage string
+	set              func(c *Config, v string) error
+}
+
+func setString(field func(c *Config) *string) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		*field(c) = v
+		return nil
+	}
+}
+
+func setInt(field func(c *Config) *int) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return err
+		}
+		*field(c) = i
+		return nil
+	}
+}
+
+func setInt64(field func(c *Config) *int64) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		i, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return err
+		}
+		*field(c) = i
+		return nil
+	}
+}
+
+func setBool(field func(c *Config) *bool) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+		*field(c) = b
+		return nil
+	}
+}
+
+func setDuration(field func(c *Config) *Duration) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*field(c) = Duration(d)
+		return nil
+	}
+}
+
+func setList(field func(c *Config) *[]string) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		items := make([]string, 0)
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		*field(c) = items
+		return nil
+	}
+}
+
+var options = []option{
+	{"listen", "PROM_LISTEN", "listen address", setString(func(c *Config) *string { return &c.Listen })},
+	{"tls-verify", "PROM_TLS_VERIFY", "verify TLS certificates of fetched pages", setBool(func(c *Config) *bool { return &c.TLSVerify })},
+	{"ca-bundle", "PROM_CA_BUNDLE", "PEM file with extra CA certificates", setString(func(c *Config) *string { return &c.CABundle })},
+	{"fetch-timeout", "PROM_FETCH_TIMEOUT", "page fetch timeout", setDuration(func(c *Config) *Duration { return &c.FetchTimeout })},
+	{"image-timeout", "PROM_IMAGE_TIMEOUT", "image probe timeout", setDuration(func(c *Config) *Duration { return &c.ImageTimeout })},
+	{"page-user-agent", "PROM_PAGE_USER_AGENT", "user agent of page requests", setString(func(c *Config) *string { return &c.PageUserAgent })},
+	{"image-user-agent", "PROM_IMAGE_USER_AGENT", "user agent of image requests", setString(func(c *Config) *string { return &c.ImageUserAgent })},
+	{"workers", "PROM_WORKERS", "image probe workers per extraction", setInt(func(c *Config) *int { return &c.Workers })},
+	{"batch-workers", "BATCH_WORKERS", "extractions running at once for all batch requests", setInt(func(c *Config) *int { return &c.BatchWorkers })},
+	{"max-body-size", "PROM_MAX_BODY_SIZE", "max bytes read from fetched page", setInt64(func(c *Config) *int64 { return &c.MaxBodySize })},
+	{"max-image-bytes", "PROM_MAX_IMAGE_BYTES", "max bytes read from image to find dimensions", setInt64(func(c *Config) *int64 { return &c.MaxImageBytes })},
+	{"max-html-size", "PROM_MAX_HTML_SIZE", "max size of posted HTML and JSON bodies", setInt64(func(c *Config) *int64 { return &c.MaxHTMLSize })},
+	{"shutdown-grace", "PROM_SHUTDOWN_GRACE", "time to finish in-flight requests on SIGTERM", setDuration(func(c *Config) *Duration { return &c.ShutdownGrace })},
+	{"cache-backend", "CACHE_BACKEND", "result cache: memory, disk or empty to disable", setString(func(c *Config) *string { return &c.Cache.Backend })},
+	{"cache-ttl", "CACHE_TTL", "result cache ttl", setDuration(func(c *Config) *Duration { return &c.Cache.TTL })},
+	{"cache-size", "CACHE_SIZE", "max entries of cache", setInt(func(c *Config) *int { return &c.Cache.Size })},
+	{"cache-max-bytes", "CACHE_MAX_BYTES", "max bytes of cache", setInt(func(c *Config) *int { return &c.Cache.MaxBytes })},
+	{"cache-dir", "CACHE_DIR", "directory of disk cache", setString(func(c *Config) *string { return &c.Cache.Dir })},
+	{"ssrf-allow", "SSRF_ALLOW", "comma separated IPs, CIDRs or hosts allowed despite SSRF protection", setList(func(c *Config) *[]string { return &c.SSRF.Allow })},
+	{"ssrf-deny", "SSRF_DENY", "comma separated IPs, CIDRs or hosts always blocked", setList(func(c *Config) *[]string { return &c.SSRF.Deny })},
+}
+
+// Load build config, later source wins: defaults, file (-config or PROM_CONFIG),
+// environment, flags
+func Load(args []string) (Config, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("prom", flag.ContinueOnError)
+	path := fs.String("config", os.Getenv("PROM_CONFIG"), "YAML or JSON config file")
+
+	// flags are applied after file and environment, keep them in order
+	type setFlag struct {
+		opt   option
+		value string
+	}
+	flags := make([]setFlag, 0)
+	for _, opt := range options {
+		opt := opt
+		record := func(v string) error {
+			flags = append(flags, setFlag{opt, v})
+			return nil
+		}
+		if opt.flag == "tls-verify" {
+			fs.BoolFunc(opt.flag, opt.usage+" (env "+opt.env+")", func(v string) error { return record(v) })
+			continue
+		}
+		fs.Func(opt.flag, opt.usage+" (env "+opt.env+")", record)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+
+	if *path != "" {
+		if err := loadFile(&cfg, *path); err != nil {
+			return cfg, err
+		}
+	}
+
+	for _, opt := range options {
+		if v, ok := os.LookupEnv(opt.env); ok {
+			if err := opt.set(&cfg, v); err != nil {
+				return cfg, fmt.Errorf("invalid %s: %w", opt.env, err)
+			}
+		}
+	}
+
+	for _, f := range flags {
+		if err := f.opt.set(&cfg, f.value); err != nil {
+			return cfg, fmt.Errorf("invalid -%s: %w", f.opt.flag, err)
+		}
+	}
+
+	return cfg, cfg.Validate()
+}
+
+// loadFile read YAML (.yaml, .yml) or JSON file over cfg
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, cfg)
+	default:
+		err = json.Unmarshal(data, cfg)
+	}
+	if err != nil {
+		return fmt.Errorf("can't parse %s: %w", path, err)
+	}
+	return nil
+}
+
+// Validate check values, all problems are reported together
+func (c Config) Validate() error {
+	errs := make([]error, 0)
+
+	if c.Listen == "" {
+		errs = append(errs, errors.New("listen can't be empty"))
+	}
+	if c.FetchTimeout <= 0 {
+		errs = append(errs, errors.New("fetch_timeout must be positive"))
+	}
+	if c.ImageTimeout <= 0 {
+		errs = append(errs, errors.New("image_timeout must be positive"))
+	}
+	if c.Workers < 1 || c.Workers > 1000 {
+		errs = append(errs, errors.New("workers must be between 1 and 1000"))
+	}
+	if c.BatchWorkers < 1 || c.BatchWorkers > 1000 {
+		errs = append(errs, errors.New("batch_workers must be between 1 and 1000"))
+	}
+	if c.MaxBodySize <= 0 {
+		errs = append(errs, errors.New("max_body_size must be positive"))
+	}
+	if c.MaxImageBytes < 32 {
+		errs = append(errs, errors.New("max_image_bytes must be at least 32"))
+	}
+	if c.MaxHTMLSize <= 0 {
+		errs = append(errs, errors.New("max_html_size must be positive"))
+	}
+	if c.ShutdownGrace < 0 {
+		errs = append(errs, errors.New("shutdown_grace can't be negative"))
+	}
+	if c.CABundle != "" {
+		if _, err := os.Stat(c.CABundle); err != nil {
+			errs = append(errs, fmt.Errorf("ca_bundle: %w", err))
+		}
+	}
+
+	switch c.Cache.Backend {
+	case "", "none", "memory":
+	case "disk":
+		if c.Cache.Dir == "" {
+			errs = append(errs, errors.New("cache.dir is required for disk cache"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unknown cache.backend %q", c.Cache.Backend))
+	}
+	if c.Scoring.MinWidth < 0 || c.Scoring.MinHeight < 0 {
+		errs = append(errs, errors.New("scoring min size can't be negative"))
+	}
+	if c.Scoring.MinAspect <= 0 || c.Scoring.MaxAspect < c.Scoring.MinAspect {
+		errs = append(errs, errors.New("scoring aspect bounds must be positive and min_aspect <= max_aspect"))
+	}
+	if c.Scoring.AreaTarget <= 0 {
+		errs = append(errs, errors.New("scoring.area_target must be positive"))
+	}
+	if c.Cache.TTL < 0 {
+		errs = append(errs, errors.New("cache.ttl can't be negative"))
+	}
+	if c.Cache.Size < 0 || c.Cache.MaxBytes < 0 {
+		errs = append(errs, errors.New("cache limits can't be negative"))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadDefault(t *testing.T) {
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Listen != ":9999" || cfg.Workers != 5 || cfg.BatchWorkers != 20 || time.Duration(cfg.FetchTimeout) != 10*time.Second {
+		t.Errorf("Load returned unexpected defaults: %+v", cfg)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "prom.yaml")
+	yaml := `listen: ":8000"
+workers: 7
+fetch_timeout: 3s
+cache:
+  backend: memory
+  ttl: 1m
+ssrf:
+  allow: ["10.0.0.1"]
+`
+	if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("PROM_WORKERS", "8")
+	t.Setenv("PROM_IMAGE_TIMEOUT", "2s")
+	t.Setenv("BATCH_WORKERS", "3")
+
+	cfg, err := Load([]string{"-config", path, "-workers", "9", "-tls-verify"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Listen != ":8000" {
+		t.Errorf("Listen = %v, want value from file", cfg.Listen)
+	}
+	if cfg.Workers != 9 {
+		t.Errorf("Workers = %v, want value from flag", cfg.Workers)
+	}
+	if cfg.BatchWorkers != 3 {
+		t.Errorf("BatchWorkers = %v, want value from env", cfg.BatchWorkers)
+	}
+	if time.Duration(cfg.ImageTimeout) != 2*time.Second {
+		t.Errorf("ImageTimeout = %v, want value from env", cfg.ImageTimeout)
+	}
+	if time.Duration(cfg.FetchTimeout) != 3*time.Second || time.Duration(cfg.Cache.TTL) != time.Minute || cfg.Cache.Backend != "memory" {
+		t.Errorf("unexpected values from file: %+v", cfg)
+	}
+	if !cfg.TLSVerify {
+		t.Errorf("TLSVerify = false, want true from flag")
+	}
+	if len(cfg.SSRF.Allow) != 1 || cfg.SSRF.Allow[0] != "10.0.0.1" {
+		t.Errorf("SSRF.Allow = %v", cfg.SSRF.Allow)
+	}
+}
+
+func TestLoadJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prom.json")
+	if err := os.WriteFile(path, []byte(`{"image_user_agent": "bot", "max_image_bytes": 1024}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load([]string{"-config", path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ImageUserAgent != "bot" || cfg.MaxImageBytes != 1024 {
+		t.Errorf("unexpected values from file: %+v", cfg)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Setenv("PROM_WORKERS", "0")
+	t.Setenv("CACHE_BACKEND", "redis")
+
+	if _, err := Load(nil); err == nil {
+		t.Errorf("Load accepted invalid config")
+	}
+
+	t.Setenv("PROM_WORKERS", "abc")
+	if _, err := Load(nil); err == nil {
+		t.Errorf("Load accepted invalid number")
+	}
+}
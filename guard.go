@@ -0,0 +1,72 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/slav123/prom/netguard"
+)
+
+var (
+	// urlGuard blocks requests to private networks, see cfg.SSRF
+	urlGuard, _ = netguard.New(nil, nil)
+
+	pageClient  *http.Client
+	imageClient *http.Client
+)
+
+func init() {
+	if err := newClients(); err != nil {
+		panic(err)
+	}
+}
+
+// initGuard configure SSRF protection from cfg.SSRF and rebuild clients
+func initGuard() error {
+	guard, err := netguard.New(cfg.SSRF.Allow, cfg.SSRF.Deny)
+	if err != nil {
+		return err
+	}
+	urlGuard = guard
+	return newClients()
+}
+
+// newClients build http clients dialing through urlGuard, cfg.TLSVerify applies to pages only,
+// images and webhooks always verify certificates
+func newClients() error {
+	tlsConfig := &tls.Config{}
+
+	if cfg.CABundle != "" {
+		pem, err := os.ReadFile(cfg.CABundle)
+		if err != nil {
+			return err
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in %s", cfg.CABundle)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	pageTLSConfig := tlsConfig.Clone()
+	pageTLSConfig.InsecureSkipVerify = !cfg.TLSVerify
+	pageClient = urlGuard.Client(&http.Transport{
+		TLSClientConfig: pageTLSConfig,
+	}, time.Duration(cfg.FetchTimeout))
+
+	imageClient = urlGuard.Client(&http.Transport{
+		TLSClientConfig: tlsConfig.Clone(),
+	}, time.Duration(cfg.ImageTimeout))
+
+	webhookClient = urlGuard.Client(&http.Transport{
+		TLSClientConfig: tlsConfig.Clone(),
+	}, 10*time.Second)
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/slav123/prom/netguard"
+)
+
+// TestMain allow loopback, test servers listen on it
+func TestMain(m *testing.M) {
+	guard, err := netguard.New([]string{"127.0.0.0/8", "::1"}, nil)
+	if err != nil {
+		panic(err)
+	}
+	urlGuard = guard
+	if err := newClients(); err != nil {
+		panic(err)
+	}
+
+	os.Exit(m.Run())
+}
+
+func TestExtractURLBlocked(t *testing.T) {
+	req := httptest.NewRequest("GET", "/url/", nil)
+
+	for _, url := range []string{"http://169.254.169.254/latest/meta-data/", "http://10.0.0.1/", "file:///etc/passwd"} {
+		result, status := extractURL(url, ExtractOptions{}, req)
+		if status != http.StatusForbidden || result.Success {
+			t.Errorf("extractURL(%s) returned %d %+v, want %d", url, status, result, http.StatusForbidden)
+		}
+	}
+}
+
+func TestClientsTLS(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	// pages follow cfg.TLSVerify, false by default
+	resp, err := pageClient.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("pageClient rejected self-signed certificate: %v", err)
+	}
+	resp.Body.Close()
+
+	for name, client := range map[string]*http.Client{"imageClient": imageClient, "webhookClient": webhookClient} {
+		if resp, err := client.Get(ts.URL); err == nil {
+			resp.Body.Close()
+			t.Errorf("%s accepted self-signed certificate", name)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package htmlutils
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	cssComment    = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	cssRule       = regexp.MustCompile(`([^{}]+)\{([^{}]*)\}`)
+	cssBackground = regexp.MustCompile(`(?i)(?:^|[;\s])background(?:-image)?\s*:\s*([^;]+)`)
+	cssURL        = regexp.MustCompile(`(?i)url\(\s*(?:"([^"]*)"|'([^']*)'|([^)'"\s]*))\s*\)`)
+	cssImageSet   = regexp.MustCompile(`(?i)(?:-webkit-)?image-set\(`)
+)
+
+// CSSBackground background image declared in stylesheet rule
+type CSSBackground struct {
+	Selector string
+	URL      string
+}
+
+// StyleBackgrounds return background image urls of declarations, like content of style attribute.
+// Only the biggest resolution of image-set() is returned
+func StyleBackgrounds(style string) []string {
+	urls := make([]string, 0)
+
+	for _, match := range cssBackground.FindAllStringSubmatch(cssComment.ReplaceAllString(style, ""), -1) {
+		value := match[1]
+
+		// pick best candidate of each image-set and drop it from value
+		for {
+			loc := cssImageSet.FindStringIndex(value)
+			if loc == nil {
+				break
+			}
+			end := closingParen(value, loc[1])
+			if url := bestImageSet(value[loc[1]:end]); url != "" {
+				urls = append(urls, url)
+			}
+			if end < len(value) {
+				end++
+			}
+			value = value[:loc[0]] + value[end:]
+		}
+
+		for _, u := range cssURL.FindAllStringSubmatch(value, -1) {
+			if url := strings.TrimSpace(u[1] + u[2] + u[3]); url != "" && !strings.HasPrefix(url, "data:") {
+				urls = append(urls, url)
+			}
+		}
+	}
+
+	return urls
+}
+
+// StylesheetBackgrounds return background images of all rules in stylesheet, rules nested
+// in @media and similar blocks are included
+func StylesheetBackgrounds(css string) []CSSBackground {
+	backgrounds := make([]CSSBackground, 0)
+
+	for _, rule := range cssRule.FindAllStringSubmatch(cssComment.ReplaceAllString(css, ""), -1) {
+		selector := strings.Join(strings.Fields(rule[1]), " ")
+		for _, url := range StyleBackgrounds(rule[2]) {
+			backgrounds = append(backgrounds, CSSBackground{Selector: selector, URL: url})
+		}
+	}
+
+	return backgrounds
+}
+
+// closingParen index of paren closing the one opened right before start, len(s) when missing
+func closingParen(s string, start int) int {
+	depth := 1
+	var quote byte
+	for i := start; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(s)
+}
+
+// bestImageSet url with highest resolution from arguments of image-set()
+func bestImageSet(args string) string {
+	best, bestDensity := "", 0.0
+
+	for _, option := range splitArgs(args) {
+		url := ""
+		rest := option
+		if m := cssURL.FindStringSubmatchIndex(option); m != nil && m[0] == 0 {
+			sub := cssURL.FindStringSubmatch(option)
+			url = sub[1] + sub[2] + sub[3]
+			rest = option[m[1]:]
+		} else if len(option) > 0 && (option[0] == '"' || option[0] == '\'') {
+			if end := strings.IndexByte(option[1:], option[0]); end >= 0 {
+				url = option[1 : end+1]
+				rest = option[end+2:]
+			}
+		}
+		url = strings.TrimSpace(url)
+		if url == "" || strings.HasPrefix(url, "data:") {
+			continue
+		}
+
+		density := 1.0
+		for _, field := range strings.Fields(rest) {
+			if d, ok := parseResolution(field); ok {
+				density = d
+			}
+		}
+		if density > bestDensity {
+			best, bestDensity = url, density
+		}
+	}
+
+	return best
+}
+
+// splitArgs split function arguments on top level commas
+func splitArgs(s string) []string {
+	args := make([]string, 0)
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			args = append(args, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	return append(args, strings.TrimSpace(s[start:]))
+}
+
+// resolutionUnits css resolution units in dppx, longer suffixes first
+var resolutionUnits = []struct {
+	suffix string
+	scale  float64
+}{
+	{"dppx", 1},
+	{"dpcm", 2.54 / 96},
+	{"dpi", 1.0 / 96},
+	{"x", 1},
+}
+
+// parseResolution read 2x, 1.5dppx or 192dpi as density
+func parseResolution(s string) (float64, bool) {
+	s = strings.ToLower(s)
+	for _, unit := range resolutionUnits {
+		if strings.HasSuffix(s, unit.suffix) {
+			d, err := strconv.ParseFloat(strings.TrimSuffix(s, unit.suffix), 64)
+			if err != nil || d <= 0 {
+				return 0, false
+			}
+			return d * unit.scale, true
+		}
+	}
+	return 0, false
+}
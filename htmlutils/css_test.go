@@ -0,0 +1,45 @@
+package htmlutils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStyleBackgrounds(t *testing.T) {
+	tests := []struct {
+		style string
+		want  []string
+	}{
+		{`background-image: url("/hero.jpg")`, []string{"/hero.jpg"}},
+		{`color: red; background: #000 url('a.png') no-repeat center / cover`, []string{"a.png"}},
+		{`background-image:url(a.jpg), linear-gradient(red, blue), url(b.jpg)`, []string{"a.jpg", "b.jpg"}},
+		{`background-image: image-set("a.jpg" 1x, "a-2x.jpg" 2x)`, []string{"a-2x.jpg"}},
+		{`background-image: -webkit-image-set(url(a.avif) 2dppx, url(b.jpg) 192dpi)`, []string{"a.avif"}},
+		{`background-image: url(data:image/gif;base64,R0lGOD)`, []string{}},
+		{`/* background: url(old.jpg) */ border-image: url(border.png)`, []string{}},
+		{`background-color: red`, []string{}},
+	}
+
+	for _, tt := range tests {
+		if got := StyleBackgrounds(tt.style); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("StyleBackgrounds(%q) = %q, want %q", tt.style, got, tt.want)
+		}
+	}
+}
+
+func TestStylesheetBackgrounds(t *testing.T) {
+	css := `.logo { background: url(/logo.svg) }
+@media (min-width: 800px) {
+	.hero,
+	.banner { background-image: url("/hero-large.jpg"); }
+}
+p { color: black }`
+
+	want := []CSSBackground{
+		{Selector: ".logo", URL: "/logo.svg"},
+		{Selector: ".hero, .banner", URL: "/hero-large.jpg"},
+	}
+	if got := StylesheetBackgrounds(css); !reflect.DeepEqual(got, want) {
+		t.Errorf("StylesheetBackgrounds = %+v, want %+v", got, want)
+	}
+}
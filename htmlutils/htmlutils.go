@@ -1,9 +1,10 @@
 package htmlutils
 
 import (
-	"fmt"
 	"io"
 	"net/url"
+	"path"
+	"strconv"
 	"strings"
 
 	"github.com/PuerkitoBio/goquery"
@@ -59,33 +60,216 @@ func Excerpt(textCopy string) string {
 	return strings.Trim(strings.Join(split[0:max], " "), " ")
 }
 
+// lazyAttrs attributes used by lazy loading scripts instead of src, in order of preference
+var lazyAttrs = []string{"data-src", "data-lazy-src", "data-original", "data-lazy", "data-url", "data-hi-res-src"}
+
+// lazySrcsetAttrs srcset attributes, lazy ones first
+var lazySrcsetAttrs = []string{"data-srcset", "data-lazy-srcset", "srcset"}
+
+// Image found in page with hints used to score it
+type Image struct {
+	URL      string
+	Alt      string
+	Hints    string // class, id and file name of image and its parents, case kept for camelCase
+	Position int    // order of image in document
+	Source   string // img, srcset or css
+	Width    int    // declared by width attribute or srcset descriptor, 0 when unknown
+	Height   int    // declared by height attribute, 0 when unknown
+}
+
 // ScrapeImg scrape all images from given copy
 func ScrapeImg(r io.Reader, url string) []string {
 	images := make([]string, 0)
 
+	for _, image := range ScrapeImages(r, url) {
+		images = append(images, image.URL)
+	}
+
+	return images
+}
+
+// ScrapeImages scrape all images with alt text and class hints, each url is returned once.
+// Lazy loading attributes, srcset, picture sources and noscript fallbacks are understood,
+// biggest declared candidate is picked. CSS backgrounds of style attributes and <style> blocks
+// are included in document order
+func ScrapeImages(r io.Reader, url string) []Image {
+	images := make([]Image, 0)
+
 	doc, err := goquery.NewDocumentFromReader(r)
 	if err != nil {
-		log.Fatal(err)
+		log.Printf("Can't parse document: %v", err)
+		return images
 	}
-	// Find the review items
-	doc.Find("img").Each(func(i int, s *goquery.Selection) {
-		src := s.AttrOr("src", "")
 
-		if strings.Contains(src, "data:image") {
+	seen := make(map[string]bool)
+	add := func(image Image) {
+		if seen[image.URL] {
 			return
 		}
+		seen[image.URL] = true
 
-		fullImageUrl := GetBaseUrlString(src, url)
-		fmt.Println(src, url)
+		image.Position = len(images)
+		images = append(images, image)
+	}
 
-		images = append(images, fullImageUrl)
+	// stylesheets usually sit in head, their backgrounds go after body content so they
+	// don't get best position
+	stylesheet := make([]Image, 0)
+
+	doc.Find("img, noscript, style, [style]").Each(func(i int, s *goquery.Selection) {
+		// inline background of any element, img included
+		if style, ok := s.Attr("style"); ok {
+			for _, src := range StyleBackgrounds(style) {
+				add(Image{
+					URL:    GetBaseUrlString(src, url),
+					Hints:  imageHints(s, src),
+					Source: "css",
+				})
+			}
+		}
 
+		switch goquery.NodeName(s) {
+		case "img":
+			if image, ok := readImage(s, url); ok {
+				add(image)
+			}
+
+		case "style":
+			for _, background := range StylesheetBackgrounds(s.Text()) {
+				stylesheet = append(stylesheet, Image{
+					URL:    GetBaseUrlString(background.URL, url),
+					Hints:  path.Base(strings.SplitN(background.URL, "?", 2)[0]) + " " + background.Selector,
+					Source: "css",
+				})
+			}
+
+		case "noscript":
+			// noscript content is not parsed when scripting is on, parse it on its own
+			fragment, err := goquery.NewDocumentFromReader(strings.NewReader(s.Text()))
+			if err != nil {
+				return
+			}
+			hints := imageHints(s, "")
+			fragment.Find("img").Each(func(i int, img *goquery.Selection) {
+				if image, ok := readImage(img, url); ok {
+					image.Hints = strings.TrimSpace(image.Hints + " " + hints)
+					add(image)
+				}
+			})
+		}
 	})
 
+	for _, image := range stylesheet {
+		add(image)
+	}
+
 	return images
 
 }
 
+// readImage pick best url of img element
+func readImage(s *goquery.Selection, url string) (Image, bool) {
+	image := Image{
+		Alt:    strings.TrimSpace(s.AttrOr("alt", "")),
+		Source: "img",
+		Width:  attrInt(s, "width"),
+		Height: attrInt(s, "height"),
+	}
+
+	src := ""
+	for _, attr := range lazyAttrs {
+		if v := strings.TrimSpace(s.AttrOr(attr, "")); v != "" && !strings.HasPrefix(v, "data:") {
+			src = v
+			break
+		}
+	}
+	if src == "" {
+		src = strings.TrimSpace(s.AttrOr("src", ""))
+	}
+	if strings.HasPrefix(src, "data:") {
+		src = ""
+	}
+
+	// srcset of image and sources of picture
+	srcset := make([]string, 0)
+	for _, attr := range lazySrcsetAttrs {
+		if v := s.AttrOr(attr, ""); v != "" {
+			srcset = append(srcset, v)
+			break
+		}
+	}
+	if parent := s.Parent(); goquery.NodeName(parent) == "picture" {
+		parent.ChildrenFiltered("source").Each(func(i int, source *goquery.Selection) {
+			for _, attr := range lazySrcsetAttrs {
+				if v := source.AttrOr(attr, ""); v != "" {
+					srcset = append(srcset, v)
+					break
+				}
+			}
+		})
+	}
+
+	candidates := make([]SrcsetCandidate, 0)
+	for _, set := range srcset {
+		for _, c := range ParseSrcset(set) {
+			if !strings.HasPrefix(c.URL, "data:") {
+				candidates = append(candidates, c)
+			}
+		}
+	}
+
+	if largest, ok := LargestSrcset(candidates); ok && (src == "" || largest.Width > 0 || largest.Density > 1) {
+		// keep aspect ratio of declared size
+		if largest.Width > 0 {
+			if image.Width > 0 && image.Height > 0 {
+				image.Height = image.Height * largest.Width / image.Width
+			} else {
+				image.Height = 0
+			}
+			image.Width = largest.Width
+		}
+		src = largest.URL
+		image.Source = "srcset"
+	}
+
+	if src == "" {
+		return image, false
+	}
+
+	image.URL = GetBaseUrlString(src, url)
+	image.Hints = imageHints(s, src)
+	return image, true
+}
+
+// attrInt read numeric attribute like width="640" or width="640px"
+func attrInt(s *goquery.Selection, attr string) int {
+	v := strings.TrimSuffix(strings.TrimSpace(s.AttrOr(attr, "")), "px")
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// imageHints collect class and id of image and up to 3 parents, plus file name
+func imageHints(s *goquery.Selection, src string) string {
+	hints := make([]string, 0)
+
+	if src != "" {
+		hints = append(hints, path.Base(strings.SplitN(src, "?", 2)[0]))
+	}
+	for node, depth := s, 0; node.Length() > 0 && depth < 4; node, depth = node.Parent(), depth+1 {
+		if class := node.AttrOr("class", ""); class != "" {
+			hints = append(hints, class)
+		}
+		if id := node.AttrOr("id", ""); id != "" {
+			hints = append(hints, id)
+		}
+	}
+
+	return strings.Join(hints, " ")
+}
+
 // GetBaseUrlString get base url
 func GetBaseUrlString(src, baseURL string) string {
 	if src == "" {
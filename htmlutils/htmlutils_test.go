@@ -3,6 +3,7 @@ package htmlutils
 import (
 	"bytes"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -87,3 +88,79 @@ func TestSearchForTitle(t *testing.T) {
 		t.Errorf("Failed to extract TestSearchForTitle %s", result)
 	}
 }
+
+func TestScrapeImages(t *testing.T) {
+	body := `<div class="site-header"><img src="/logo.png" alt="Site"></div>
+<article id="post"><p><img src="photo.jpg?w=800" class="wp-image"></p></article>
+<img src="/logo.png"><img src="data:image/gif;base64,R0lGOD">`
+
+	images := ScrapeImages(strings.NewReader(body), "https://example.com/blog/post")
+	if len(images) != 2 {
+		t.Fatalf("ScrapeImages returned %d images, want 2: %+v", len(images), images)
+	}
+
+	if images[0].URL != "https://example.com/logo.png" || images[0].Alt != "Site" || images[0].Position != 0 {
+		t.Errorf("ScrapeImages first image = %+v", images[0])
+	}
+	if !strings.Contains(images[0].Hints, "site-header") || !strings.Contains(images[0].Hints, "logo.png") {
+		t.Errorf("ScrapeImages hints = %q", images[0].Hints)
+	}
+	if images[1].URL != "https://example.com/blog/photo.jpg?w=800" || !strings.Contains(images[1].Hints, "post") {
+		t.Errorf("ScrapeImages second image = %+v", images[1])
+	}
+}
+
+func TestScrapeImagesResponsive(t *testing.T) {
+	body := `<img src="data:image/gif;base64,R0lGOD" data-src="/lazy.jpg" width="40" height="30">
+<img src="/small.jpg" srcset="/small.jpg 320w, /large.jpg 1280w" width="320" height="180">
+<picture class="hero"><source srcset="/hero.webp 1600w" type="image/webp"><img src="/hero.jpg"></picture>
+<img class="lazyload" data-srcset="/a.jpg 1x, /a@2x.jpg 2x">
+<noscript><img src="/fallback.jpg" alt="Fallback"></noscript>
+<noscript><img src="/lazy.jpg"></noscript>`
+
+	images := ScrapeImages(strings.NewReader(body), "https://example.com/")
+
+	want := []Image{
+		{URL: "https://example.com/lazy.jpg", Source: "img", Width: 40, Height: 30},
+		{URL: "https://example.com/large.jpg", Source: "srcset", Width: 1280, Height: 720},
+		{URL: "https://example.com/hero.webp", Source: "srcset", Width: 1600},
+		{URL: "https://example.com/a@2x.jpg", Source: "srcset"},
+		{URL: "https://example.com/fallback.jpg", Source: "img", Alt: "Fallback"},
+	}
+	if len(images) != len(want) {
+		t.Fatalf("ScrapeImages returned %d images, want %d: %+v", len(images), len(want), images)
+	}
+	for i, w := range want {
+		got := images[i]
+		if got.URL != w.URL || got.Source != w.Source || got.Width != w.Width || got.Height != w.Height || got.Alt != w.Alt || got.Position != i {
+			t.Errorf("ScrapeImages[%d] = %+v, want %+v", i, got, w)
+		}
+	}
+	if !strings.Contains(images[2].Hints, "hero") {
+		t.Errorf("picture hints = %q", images[2].Hints)
+	}
+}
+
+func TestScrapeImagesBackgrounds(t *testing.T) {
+	body := `<html><head><style>.site-logo { background: url(/logo.png) }</style></head><body>
+<section class="hero" style="background-image: url('img/hero.jpg')"></section>
+<img src="/photo.jpg" style="background: url(/blog/img/hero.jpg)">
+</body></html>`
+
+	images := ScrapeImages(strings.NewReader(body), "https://example.com/blog/")
+	if len(images) != 3 {
+		t.Fatalf("ScrapeImages returned %d images, want 3: %+v", len(images), images)
+	}
+
+	if images[0].URL != "https://example.com/blog/img/hero.jpg" || images[0].Source != "css" || !strings.Contains(images[0].Hints, "hero") {
+		t.Errorf("ScrapeImages inline background = %+v", images[0])
+	}
+	if images[1].URL != "https://example.com/photo.jpg" || images[1].Source != "img" {
+		t.Errorf("ScrapeImages img = %+v", images[1])
+	}
+	// stylesheet in head goes after body content
+	if images[2].URL != "https://example.com/logo.png" || images[2].Source != "css" || images[2].Position != 2 ||
+		!strings.Contains(images[2].Hints, "site-logo") {
+		t.Errorf("ScrapeImages stylesheet image = %+v", images[2])
+	}
+}
@@ -0,0 +1,258 @@
+package htmlutils
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// articleTypes schema.org types we treat as an article
+var articleTypes = map[string]bool{
+	"Article":             true,
+	"NewsArticle":         true,
+	"BlogPosting":         true,
+	"LiveBlogPosting":     true,
+	"ReportageNews":       true,
+	"AnalysisNewsArticle": true,
+	"OpinionNewsArticle":  true,
+	"TechArticle":         true,
+	"ScholarlyArticle":    true,
+}
+
+// Article holds metadata read from schema.org JSON-LD blocks
+type Article struct {
+	Type          string
+	Headline      string
+	Author        string
+	DatePublished string
+	DateModified  string
+	Image         string
+	Publisher     string
+	Section       string
+}
+
+// SearchForArticleFromDoc walks every application/ld+json script and returns
+// the first schema.org article found, including ones inside @graph arrays
+func SearchForArticleFromDoc(doc *goquery.Document) (Article, bool) {
+	var article Article
+	found := false
+
+	doc.Find(`script[type="application/ld+json"]`).EachWithBreak(func(i int, s *goquery.Selection) bool {
+		var data interface{}
+		if err := json.Unmarshal([]byte(strings.TrimSpace(s.Text())), &data); err != nil {
+			return true
+		}
+
+		// collect nodes with @id, so references like {"@id": "#author"} can be followed
+		ids := make(map[string]map[string]interface{})
+		indexIDs(data, ids)
+
+		node := findArticle(data)
+		if node == nil {
+			return true
+		}
+
+		article = Article{
+			Type:          ldType(node),
+			Headline:      ldString(node["headline"], ids),
+			Author:        ldNames(node["author"], ids),
+			DatePublished: ldString(node["datePublished"], ids),
+			DateModified:  ldString(node["dateModified"], ids),
+			Image:         ldURL(node["image"], ids),
+			Publisher:     ldNames(node["publisher"], ids),
+			Section:       ldList(node["articleSection"], ids),
+		}
+		if article.Headline == "" {
+			article.Headline = ldString(node["name"], ids)
+		}
+		found = true
+		return false
+	})
+
+	return article, found
+}
+
+// indexIDs collect every object with an @id
+func indexIDs(v interface{}, ids map[string]map[string]interface{}) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if id, ok := t["@id"].(string); ok && len(t) > 1 {
+			if _, exists := ids[id]; !exists {
+				ids[id] = t
+			}
+		}
+		for _, key := range sortedKeys(t) {
+			indexIDs(t[key], ids)
+		}
+	case []interface{}:
+		for _, child := range t {
+			indexIDs(child, ids)
+		}
+	}
+}
+
+// findArticle depth first search for article typed object
+func findArticle(v interface{}) map[string]interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if isArticle(t) {
+			return t
+		}
+		// look at @graph and mainEntity first, then everything else
+		for _, key := range []string{"@graph", "mainEntity", "mainEntityOfPage"} {
+			if found := findArticle(t[key]); found != nil {
+				return found
+			}
+		}
+		for _, key := range sortedKeys(t) {
+			if key == "@graph" || key == "mainEntity" || key == "mainEntityOfPage" {
+				continue
+			}
+			if found := findArticle(t[key]); found != nil {
+				return found
+			}
+		}
+	case []interface{}:
+		for _, child := range t {
+			if found := findArticle(child); found != nil {
+				return found
+			}
+		}
+	}
+	return nil
+}
+
+// sortedKeys keys of object in fixed order, so same document always gives same result
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// isArticle check @type, which might be string or array
+func isArticle(node map[string]interface{}) bool {
+	switch t := node["@type"].(type) {
+	case string:
+		return articleTypes[t]
+	case []interface{}:
+		for _, v := range t {
+			if s, ok := v.(string); ok && articleTypes[s] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ldType return first @type of node
+func ldType(node map[string]interface{}) string {
+	switch t := node["@type"].(type) {
+	case string:
+		return t
+	case []interface{}:
+		for _, v := range t {
+			if s, ok := v.(string); ok && articleTypes[s] {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// resolve follow @id reference if object has nothing else
+func resolve(v interface{}, ids map[string]map[string]interface{}) interface{} {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return v
+	}
+	if id, ok := obj["@id"].(string); ok && len(obj) == 1 {
+		if target, ok := ids[id]; ok {
+			return target
+		}
+	}
+	return obj
+}
+
+// ldString read plain value
+func ldString(v interface{}, ids map[string]map[string]interface{}) string {
+	switch t := resolve(v, ids).(type) {
+	case string:
+		return strings.TrimSpace(t)
+	case []interface{}:
+		if len(t) > 0 {
+			return ldString(t[0], ids)
+		}
+	case map[string]interface{}:
+		if s, ok := t["@value"].(string); ok {
+			return strings.TrimSpace(s)
+		}
+	}
+	return ""
+}
+
+// ldNames read name of person / organization, multiple names are joined by comma
+func ldNames(v interface{}, ids map[string]map[string]interface{}) string {
+	names := make([]string, 0)
+
+	switch t := resolve(v, ids).(type) {
+	case string:
+		names = append(names, strings.TrimSpace(t))
+	case map[string]interface{}:
+		names = append(names, ldString(t["name"], ids))
+	case []interface{}:
+		for _, item := range t {
+			names = append(names, ldNames(item, ids))
+		}
+	}
+
+	return joinNonEmpty(names)
+}
+
+// ldURL read url from string, ImageObject or array of them
+func ldURL(v interface{}, ids map[string]map[string]interface{}) string {
+	switch t := resolve(v, ids).(type) {
+	case string:
+		return strings.TrimSpace(t)
+	case map[string]interface{}:
+		if u := ldString(t["url"], ids); u != "" {
+			return u
+		}
+		return ldString(t["contentUrl"], ids)
+	case []interface{}:
+		for _, item := range t {
+			if u := ldURL(item, ids); u != "" {
+				return u
+			}
+		}
+	}
+	return ""
+}
+
+// ldList read string or array of strings
+func ldList(v interface{}, ids map[string]map[string]interface{}) string {
+	switch t := resolve(v, ids).(type) {
+	case []interface{}:
+		items := make([]string, 0, len(t))
+		for _, item := range t {
+			items = append(items, ldString(item, ids))
+		}
+		return joinNonEmpty(items)
+	default:
+		return ldString(t, ids)
+	}
+}
+
+func joinNonEmpty(items []string) string {
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		if item != "" {
+			out = append(out, item)
+		}
+	}
+	return strings.Join(out, ", ")
+}
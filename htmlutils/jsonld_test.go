@@ -0,0 +1,120 @@
+package htmlutils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestSearchForArticle(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		expected Article
+	}{
+		{
+			name: "plain NewsArticle",
+			html: `<script type="application/ld+json">{
+				"@context": "https://schema.org",
+				"@type": "NewsArticle",
+				"headline": "Big news",
+				"author": {"@type": "Person", "name": "Jane Doe"},
+				"datePublished": "2024-06-01T10:00:00Z",
+				"dateModified": "2024-06-02T10:00:00Z",
+				"image": ["https://example.com/a.jpg", "https://example.com/b.jpg"],
+				"publisher": {"@type": "Organization", "name": "Example News"},
+				"articleSection": ["World", "Politics"]
+			}</script>`,
+			expected: Article{
+				Type:          "NewsArticle",
+				Headline:      "Big news",
+				Author:        "Jane Doe",
+				DatePublished: "2024-06-01T10:00:00Z",
+				DateModified:  "2024-06-02T10:00:00Z",
+				Image:         "https://example.com/a.jpg",
+				Publisher:     "Example News",
+				Section:       "World, Politics",
+			},
+		},
+		{
+			name: "graph with references",
+			html: `<script type="application/ld+json">{
+				"@context": "https://schema.org",
+				"@graph": [
+					{"@type": "WebPage", "@id": "https://example.com/post#webpage"},
+					{"@type": ["Article", "BlogPosting"], "headline": "Post", "author": {"@id": "#jane"},
+					 "publisher": {"@id": "#org"}, "image": {"@id": "#img"}, "datePublished": "2024-01-01"},
+					{"@type": "Person", "@id": "#jane", "name": "Jane"},
+					{"@type": "Organization", "@id": "#org", "name": "Blog"},
+					{"@type": "ImageObject", "@id": "#img", "url": "https://example.com/img.png"}
+				]
+			}</script>`,
+			expected: Article{
+				Type:          "Article",
+				Headline:      "Post",
+				Author:        "Jane",
+				DatePublished: "2024-01-01",
+				Image:         "https://example.com/img.png",
+				Publisher:     "Blog",
+			},
+		},
+		{
+			name: "nested in mainEntity after broken block",
+			html: `<script type="application/ld+json">{ broken</script>
+			<script type="application/ld+json">{
+				"@type": "WebPage",
+				"mainEntity": {"@type": "BlogPosting", "name": "Named", "author": ["A", {"name": "B"}]}
+			}</script>`,
+			expected: Article{
+				Type:     "BlogPosting",
+				Headline: "Named",
+				Author:   "A, B",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, ok := SearchForArticleFromDoc(doc)
+			if !ok {
+				t.Fatalf("SearchForArticleFromDoc found nothing")
+			}
+			if result != tt.expected {
+				t.Errorf("SearchForArticleFromDoc = %+v, want %+v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSearchForArticleMissing(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<script type="application/ld+json">{"@type": "Organization", "name": "x"}</script>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := SearchForArticleFromDoc(doc); ok {
+		t.Errorf("SearchForArticleFromDoc returned article for Organization only")
+	}
+}
+
+func TestSearchForArticleNestedOrder(t *testing.T) {
+	body := `<script type="application/ld+json">{"@type": "WebPage",
+"isPartOf": {"@type": "Article", "headline": "part"},
+"about": {"@type": "NewsArticle", "headline": "about"},
+"hasPart": {"@type": "BlogPosting", "headline": "has part"}}</script>`
+
+	// map order is random, same article must win every time
+	for i := 0; i < 20; i++ {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if article, ok := SearchForArticleFromDoc(doc); !ok || article.Headline != "about" {
+			t.Fatalf("SearchForArticleFromDoc returned %+v, %v, want headline about", article, ok)
+		}
+	}
+}
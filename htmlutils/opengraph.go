@@ -0,0 +1,194 @@
+package htmlutils
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// OpenGraphMedia single og:image, og:video or og:audio with its structured properties
+type OpenGraphMedia struct {
+	URL       string `json:"url"`
+	SecureURL string `json:"secure_url,omitempty"`
+	Type      string `json:"type,omitempty"`
+	Width     int    `json:"width,omitempty"`
+	Height    int    `json:"height,omitempty"`
+	Alt       string `json:"alt,omitempty"`
+}
+
+// OpenGraph holds og:* properties, Properties keeps every raw value in document order
+type OpenGraph struct {
+	Title           string              `json:"title,omitempty"`
+	Description     string              `json:"description,omitempty"`
+	SiteName        string              `json:"site_name,omitempty"`
+	Type            string              `json:"type,omitempty"`
+	URL             string              `json:"url,omitempty"`
+	Locale          string              `json:"locale,omitempty"`
+	LocaleAlternate []string            `json:"locale_alternate,omitempty"`
+	Images          []OpenGraphMedia    `json:"images,omitempty"`
+	Videos          []OpenGraphMedia    `json:"videos,omitempty"`
+	Audio           []OpenGraphMedia    `json:"audio,omitempty"`
+	Properties      map[string][]string `json:"properties,omitempty"`
+}
+
+// TwitterCard holds twitter:* properties, fields take first value and Properties keeps every raw value in document order
+type TwitterCard struct {
+	Card        string              `json:"card,omitempty"`
+	Site        string              `json:"site,omitempty"`
+	Creator     string              `json:"creator,omitempty"`
+	Title       string              `json:"title,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Image       string              `json:"image,omitempty"`
+	ImageAlt    string              `json:"image_alt,omitempty"`
+	Player      string              `json:"player,omitempty"`
+	Properties  map[string][]string `json:"properties,omitempty"`
+}
+
+// metaKey return property or name attribute of meta tag
+func metaKey(s *goquery.Selection) string {
+	if property, ok := s.Attr("property"); ok && property != "" {
+		return strings.ToLower(strings.TrimSpace(property))
+	}
+	return strings.ToLower(strings.TrimSpace(s.AttrOr("name", "")))
+}
+
+// SearchForOpenGraphFromDoc read all og:* meta tags
+func SearchForOpenGraphFromDoc(doc *goquery.Document) OpenGraph {
+	og := OpenGraph{
+		Properties: make(map[string][]string),
+	}
+
+	doc.Find("meta").Each(func(i int, s *goquery.Selection) {
+		key := metaKey(s)
+		if !strings.HasPrefix(key, "og:") {
+			return
+		}
+		content := strings.TrimSpace(s.AttrOr("content", ""))
+		if content == "" {
+			return
+		}
+
+		og.Properties[key] = append(og.Properties[key], content)
+
+		switch key {
+		case "og:title":
+			og.Title = content
+		case "og:description":
+			og.Description = content
+		case "og:site_name":
+			og.SiteName = content
+		case "og:type":
+			og.Type = content
+		case "og:url":
+			og.URL = content
+		case "og:locale":
+			og.Locale = content
+		case "og:locale:alternate":
+			og.LocaleAlternate = append(og.LocaleAlternate, content)
+		default:
+			for _, kind := range []string{"image", "video", "audio"} {
+				if key == "og:"+kind || strings.HasPrefix(key, "og:"+kind+":") {
+					property := strings.TrimPrefix(strings.TrimPrefix(key, "og:"+kind), ":")
+					switch kind {
+					case "image":
+						og.Images = addMediaProperty(og.Images, property, content)
+					case "video":
+						og.Videos = addMediaProperty(og.Videos, property, content)
+					case "audio":
+						og.Audio = addMediaProperty(og.Audio, property, content)
+					}
+				}
+			}
+		}
+	})
+
+	return og
+}
+
+// addMediaProperty og:image starts a new item, og:image:url and structured properties go to the last one
+func addMediaProperty(media []OpenGraphMedia, property, content string) []OpenGraphMedia {
+	if property == "" {
+		return append(media, OpenGraphMedia{URL: content})
+	}
+	// og:image:url repeating og:image or following its structured properties
+	if property == "url" {
+		if len(media) == 0 || (media[len(media)-1].URL != "" && media[len(media)-1].URL != content) {
+			return append(media, OpenGraphMedia{URL: content})
+		}
+		media[len(media)-1].URL = content
+		return media
+	}
+
+	// structured property before any url, keep it anyway
+	if len(media) == 0 {
+		media = append(media, OpenGraphMedia{})
+	}
+	last := &media[len(media)-1]
+
+	switch property {
+	case "secure_url":
+		last.SecureURL = content
+		if last.URL == "" {
+			last.URL = content
+		}
+	case "type":
+		last.Type = content
+	case "width":
+		last.Width, _ = strconv.Atoi(content)
+	case "height":
+		last.Height, _ = strconv.Atoi(content)
+	case "alt":
+		last.Alt = content
+	}
+
+	return media
+}
+
+// SearchForTwitterCardFromDoc read all twitter:* meta tags
+func SearchForTwitterCardFromDoc(doc *goquery.Document) TwitterCard {
+	card := TwitterCard{
+		Properties: make(map[string][]string),
+	}
+
+	doc.Find("meta").Each(func(i int, s *goquery.Selection) {
+		key := metaKey(s)
+		if !strings.HasPrefix(key, "twitter:") {
+			return
+		}
+		content := strings.TrimSpace(s.AttrOr("content", s.AttrOr("value", "")))
+		if content == "" {
+			return
+		}
+
+		// fields take first value, same as browsers and card validators
+		first := len(card.Properties[key]) == 0
+		card.Properties[key] = append(card.Properties[key], content)
+		if !first {
+			return
+		}
+
+		switch key {
+		case "twitter:card":
+			card.Card = content
+		case "twitter:site":
+			card.Site = content
+		case "twitter:creator":
+			card.Creator = content
+		case "twitter:title":
+			card.Title = content
+		case "twitter:description":
+			card.Description = content
+		case "twitter:image", "twitter:image:src":
+			if card.Image == "" {
+				card.Image = content
+			}
+		case "twitter:image:alt":
+			card.ImageAlt = content
+		case "twitter:player":
+			card.Player = content
+		}
+	})
+
+	return card
+}
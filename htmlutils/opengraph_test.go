@@ -0,0 +1,104 @@
+package htmlutils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const openGraphHTML = `<html><head>
+<meta property="og:title" content="Title">
+<meta property="og:site_name" content="Site">
+<meta property="og:type" content="article">
+<meta property="og:locale" content="en_US">
+<meta property="og:locale:alternate" content="pl_PL">
+<meta property="og:image" content="http://example.com/1.jpg">
+<meta property="og:image:secure_url" content="https://example.com/1.jpg">
+<meta property="og:image:width" content="1200">
+<meta property="og:image:height" content="630">
+<meta property="og:image:alt" content="first">
+<meta property="og:image" content="https://example.com/2.jpg">
+<meta property="og:image:url" content="https://example.com/2.jpg">
+<meta property="og:image:url" content="https://example.com/3.jpg">
+<meta property="og:video:url" content="https://example.com/v.mp4">
+<meta property="og:video:type" content="video/mp4">
+<meta name="twitter:card" content="summary_large_image">
+<meta name="twitter:creator" content="@jane">
+<meta name="twitter:image" content="https://example.com/t.jpg">
+<meta name="twitter:image" content="https://example.com/ignored.jpg">
+</head><body></body></html>`
+
+func TestSearchForOpenGraph(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(openGraphHTML))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	og := SearchForOpenGraphFromDoc(doc)
+	if og.Title != "Title" || og.SiteName != "Site" || og.Type != "article" || og.Locale != "en_US" {
+		t.Errorf("SearchForOpenGraphFromDoc basic properties = %+v", og)
+	}
+	if !reflect.DeepEqual(og.LocaleAlternate, []string{"pl_PL"}) {
+		t.Errorf("LocaleAlternate = %v", og.LocaleAlternate)
+	}
+
+	images := []OpenGraphMedia{
+		{URL: "http://example.com/1.jpg", SecureURL: "https://example.com/1.jpg", Width: 1200, Height: 630, Alt: "first"},
+		{URL: "https://example.com/2.jpg"},
+		{URL: "https://example.com/3.jpg"},
+	}
+	if !reflect.DeepEqual(og.Images, images) {
+		t.Errorf("Images = %+v, want %+v", og.Images, images)
+	}
+
+	videos := []OpenGraphMedia{{URL: "https://example.com/v.mp4", Type: "video/mp4"}}
+	if !reflect.DeepEqual(og.Videos, videos) {
+		t.Errorf("Videos = %+v, want %+v", og.Videos, videos)
+	}
+
+	if len(og.Properties["og:image"]) != 2 || len(og.Properties["og:image:url"]) != 2 {
+		t.Errorf("Properties[og:image] = %v", og.Properties["og:image"])
+	}
+}
+
+func TestSearchForTwitterCard(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(openGraphHTML))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	card := SearchForTwitterCardFromDoc(doc)
+	if card.Card != "summary_large_image" || card.Creator != "@jane" || card.Image != "https://example.com/t.jpg" {
+		t.Errorf("SearchForTwitterCardFromDoc = %+v", card)
+	}
+	images := []string{"https://example.com/t.jpg", "https://example.com/ignored.jpg"}
+	if !reflect.DeepEqual(card.Properties["twitter:image"], images) {
+		t.Errorf("Properties[twitter:image] = %v, want %v", card.Properties["twitter:image"], images)
+	}
+}
+
+func TestAddMediaProperty(t *testing.T) {
+	tests := []struct {
+		name       string
+		properties [][2]string
+		want       []OpenGraphMedia
+	}{
+		{"url after image", [][2]string{{"", "a.jpg"}, {"url", "a.jpg"}}, []OpenGraphMedia{{URL: "a.jpg"}}},
+		{"url only", [][2]string{{"url", "a.jpg"}, {"width", "10"}}, []OpenGraphMedia{{URL: "a.jpg", Width: 10}}},
+		{"url after properties", [][2]string{{"width", "10"}, {"url", "a.jpg"}}, []OpenGraphMedia{{URL: "a.jpg", Width: 10}}},
+		{"different url", [][2]string{{"", "a.jpg"}, {"url", "b.jpg"}}, []OpenGraphMedia{{URL: "a.jpg"}, {URL: "b.jpg"}}},
+		{"repeated image", [][2]string{{"", "a.jpg"}, {"", "a.jpg"}}, []OpenGraphMedia{{URL: "a.jpg"}, {URL: "a.jpg"}}},
+	}
+
+	for _, tt := range tests {
+		var media []OpenGraphMedia
+		for _, p := range tt.properties {
+			media = addMediaProperty(media, p[0], p[1])
+		}
+		if !reflect.DeepEqual(media, tt.want) {
+			t.Errorf("%s: addMediaProperty = %+v, want %+v", tt.name, media, tt.want)
+		}
+	}
+}
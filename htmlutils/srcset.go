@@ -0,0 +1,92 @@
+package htmlutils
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// SrcsetCandidate single entry of srcset attribute, Width is set for "800w"
+// descriptors, Density for "2x" (1 when there is no descriptor)
+type SrcsetCandidate struct {
+	URL     string
+	Width   int
+	Density float64
+}
+
+// ParseSrcset parse srcset attribute, following HTML spec closely enough for
+// URLs with commas inside (like CDN transformations)
+func ParseSrcset(srcset string) []SrcsetCandidate {
+	candidates := make([]SrcsetCandidate, 0)
+	s := srcset
+
+	for {
+		s = strings.TrimLeftFunc(s, func(r rune) bool { return unicode.IsSpace(r) || r == ',' })
+		if s == "" {
+			return candidates
+		}
+
+		// url is everything up to whitespace
+		end := strings.IndexFunc(s, unicode.IsSpace)
+		if end < 0 {
+			end = len(s)
+		}
+		url := s[:end]
+		s = s[end:]
+
+		var descriptor string
+		if strings.HasSuffix(url, ",") {
+			// "a.jpg, b.jpg 2x" - no descriptor for a.jpg
+			url = strings.TrimRight(url, ",")
+		} else {
+			// descriptor ends on comma outside of parens
+			depth, i := 0, 0
+			for ; i < len(s); i++ {
+				if s[i] == '(' {
+					depth++
+				} else if s[i] == ')' && depth > 0 {
+					depth--
+				} else if s[i] == ',' && depth == 0 {
+					break
+				}
+			}
+			descriptor = strings.TrimSpace(s[:i])
+			s = s[i:]
+		}
+
+		if url == "" {
+			continue
+		}
+
+		candidate := SrcsetCandidate{URL: url, Density: 1}
+		for _, d := range strings.Fields(descriptor) {
+			switch {
+			case strings.HasSuffix(d, "w"):
+				if w, err := strconv.Atoi(strings.TrimSuffix(d, "w")); err == nil {
+					candidate.Width = w
+				}
+			case strings.HasSuffix(d, "x"):
+				if x, err := strconv.ParseFloat(strings.TrimSuffix(d, "x"), 64); err == nil {
+					candidate.Density = x
+				}
+			}
+		}
+		candidates = append(candidates, candidate)
+	}
+}
+
+// LargestSrcset return candidate with biggest declared width, or density when
+// widths are not declared
+func LargestSrcset(candidates []SrcsetCandidate) (SrcsetCandidate, bool) {
+	if len(candidates) == 0 {
+		return SrcsetCandidate{}, false
+	}
+
+	best := candidates[0]
+	for _, c := range candidates[1:] {
+		if c.Width > best.Width || (c.Width == best.Width && c.Density > best.Density) {
+			best = c
+		}
+	}
+	return best, true
+}
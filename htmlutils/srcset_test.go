@@ -0,0 +1,59 @@
+package htmlutils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSrcset(t *testing.T) {
+	tests := []struct {
+		srcset   string
+		expected []SrcsetCandidate
+	}{
+		{
+			srcset: "small.jpg 480w, medium.jpg 800w,large.jpg 1200w",
+			expected: []SrcsetCandidate{
+				{URL: "small.jpg", Width: 480, Density: 1},
+				{URL: "medium.jpg", Width: 800, Density: 1},
+				{URL: "large.jpg", Width: 1200, Density: 1},
+			},
+		},
+		{
+			srcset: "a.jpg, b.jpg 2x, c.jpg 1.5x",
+			expected: []SrcsetCandidate{
+				{URL: "a.jpg", Density: 1},
+				{URL: "b.jpg", Density: 2},
+				{URL: "c.jpg", Density: 1.5},
+			},
+		},
+		{
+			srcset: "https://cdn.example.com/w_400,h_300/img.jpg 400w, https://cdn.example.com/w_800,h_600/img.jpg 800w",
+			expected: []SrcsetCandidate{
+				{URL: "https://cdn.example.com/w_400,h_300/img.jpg", Width: 400, Density: 1},
+				{URL: "https://cdn.example.com/w_800,h_600/img.jpg", Width: 800, Density: 1},
+			},
+		},
+		{
+			srcset:   "",
+			expected: []SrcsetCandidate{},
+		},
+	}
+
+	for _, tt := range tests {
+		if result := ParseSrcset(tt.srcset); !reflect.DeepEqual(result, tt.expected) {
+			t.Errorf("ParseSrcset(%q) = %+v, want %+v", tt.srcset, result, tt.expected)
+		}
+	}
+}
+
+func TestLargestSrcset(t *testing.T) {
+	largest, ok := LargestSrcset(ParseSrcset("a.jpg 480w, b.jpg 1200w, c.jpg 800w"))
+	if !ok || largest.URL != "b.jpg" {
+		t.Errorf("LargestSrcset = %+v", largest)
+	}
+
+	largest, ok = LargestSrcset(ParseSrcset("a.jpg, b.jpg 3x, c.jpg 2x"))
+	if !ok || largest.URL != "b.jpg" {
+		t.Errorf("LargestSrcset = %+v", largest)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	imageCacheSize        = 10000
+	imageCacheTTL         = 24 * time.Hour
+	imageCacheNegativeTTL = 10 * time.Minute
+)
+
+// imageCache dimensions of already probed images, shared by all requests
+var imageCache = NewImageCache(imageCacheSize, imageCacheTTL, imageCacheNegativeTTL)
+
+// ImageCacheStats hit / miss counters reported on /status
+type ImageCacheStats struct {
+	Entries int    `json:"entries"`
+	Hits    uint64 `json:"hits"`
+	Misses  uint64 `json:"misses"`
+}
+
+type imageCacheEntry struct {
+	result    ImageResult
+	expiresAt time.Time
+}
+
+// ImageCache bounded LRU of ImageResult keyed by image url, failed probes are
+// kept for shorter time so broken images are not retried on every page
+type ImageCache struct {
+	mu          sync.Mutex
+	size        int
+	ttl         time.Duration
+	negativeTTL time.Duration
+	ll          *list.List
+	items       map[string]*list.Element
+	hits        atomic.Uint64
+	misses      atomic.Uint64
+}
+
+// NewImageCache create cache holding up to size results
+func NewImageCache(size int, ttl, negativeTTL time.Duration) *ImageCache {
+	return &ImageCache{
+		size:        size,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		ll:          list.New(),
+		items:       make(map[string]*list.Element),
+	}
+}
+
+// Get cached result, expired entries are dropped
+func (c *ImageCache) Get(url string) (ImageResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[url]
+	if !ok {
+		c.misses.Add(1)
+		return ImageResult{}, false
+	}
+
+	entry := el.Value.(*imageCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.ll.Remove(el)
+		delete(c.items, url)
+		c.misses.Add(1)
+		return ImageResult{}, false
+	}
+
+	c.ll.MoveToFront(el)
+	c.hits.Add(1)
+	return entry.result, true
+}
+
+// Set store result, ok false marks failed probe
+func (c *ImageCache) Set(result ImageResult, ok bool) {
+	ttl := c.ttl
+	if !ok {
+		ttl = c.negativeTTL
+	}
+	if ttl <= 0 || c.size <= 0 {
+		return
+	}
+
+	entry := &imageCacheEntry{
+		result:    result,
+		expiresAt: time.Now().Add(ttl),
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, exists := c.items[result.URL]; exists {
+		el.Value = entry
+		c.ll.MoveToFront(el)
+	} else {
+		c.items[result.URL] = c.ll.PushFront(entry)
+	}
+
+	for c.ll.Len() > c.size {
+		el := c.ll.Back()
+		c.ll.Remove(el)
+		delete(c.items, el.Value.(*imageCacheEntry).result.URL)
+	}
+}
+
+// Stats current counters
+func (c *ImageCache) Stats() ImageCacheStats {
+	c.mu.Lock()
+	entries := c.ll.Len()
+	c.mu.Unlock()
+
+	return ImageCacheStats{
+		Entries: entries,
+		Hits:    c.hits.Load(),
+		Misses:  c.misses.Load(),
+	}
+}
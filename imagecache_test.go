@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestImageCache(t *testing.T) {
+	c := NewImageCache(2, time.Hour, 0)
+
+	c.Set(ImageResult{URL: "a", Width: 1, Height: 1, Area: 1}, true)
+	c.Set(ImageResult{URL: "b", Width: 1, Height: 1, Area: 1}, true)
+	c.Get("a")
+	c.Set(ImageResult{URL: "c", Width: 1, Height: 1, Area: 1}, true)
+
+	if _, ok := c.Get("b"); ok {
+		t.Errorf("ImageCache kept least recently used entry")
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Errorf("ImageCache evicted recently used entry")
+	}
+
+	// negative ttl of 0 disables caching failures
+	c.Set(ImageResult{URL: "failed"}, false)
+	if _, ok := c.Get("failed"); ok {
+		t.Errorf("ImageCache kept failed probe")
+	}
+
+	if stats := c.Stats(); stats.Hits != 2 || stats.Misses != 2 || stats.Entries != 2 {
+		t.Errorf("ImageCache.Stats = %+v", stats)
+	}
+}
+
+func TestGetAllImagesCached(t *testing.T) {
+	var probes int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&probes, 1)
+		if r.URL.Path == "/missing.png" {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeFile(w, r, "imageutils/samples/file.png")
+	}))
+	defer ts.Close()
+
+	saved := imageCache
+	imageCache = NewImageCache(100, time.Hour, time.Hour)
+	defer func() { imageCache = saved }()
+
+	page := fmt.Sprintf(`<img src="%s/file.png"><img src="%s/missing.png">`, ts.URL, ts.URL)
+	req := httptest.NewRequest("GET", "/url/", nil)
+
+	for i := 0; i < 2; i++ {
+		candidates := GetAllImages(strings.NewReader(page), ts.URL, "", nil, req)
+		if len(candidates) != 2 || candidates[0].URL != ts.URL+"/file.png" {
+			t.Errorf("GetAllImages returned %+v", candidates)
+		}
+	}
+
+	if probes != 2 {
+		t.Errorf("images were probed %d times, want 2", probes)
+	}
+	if stats := imageCache.Stats(); stats.Hits != 2 || stats.Misses != 2 {
+		t.Errorf("ImageCache.Stats = %+v", stats)
+	}
+}
+
+func TestGetAllImagesCancelled(t *testing.T) {
+	var probes int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&probes, 1)
+		http.ServeFile(w, r, "imageutils/samples/file.png")
+	}))
+	defer ts.Close()
+
+	saved := imageCache
+	imageCache = NewImageCache(100, time.Hour, time.Hour)
+	defer func() { imageCache = saved }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest("GET", "/url/", nil).WithContext(ctx)
+
+	page := fmt.Sprintf(`<img src="%s/a.png"><img src="%s/b.png">`, ts.URL, ts.URL)
+	for _, candidate := range GetAllImages(strings.NewReader(page), ts.URL, "", nil, req) {
+		if candidate.Score.Eligible {
+			t.Errorf("GetAllImages returned eligible %v for cancelled request", candidate.URL)
+		}
+	}
+	if probes != 0 {
+		t.Errorf("images were probed %d times after cancel", probes)
+	}
+	if stats := imageCache.Stats(); stats.Entries != 0 {
+		t.Errorf("cancelled probes were cached: %+v", stats)
+	}
+}
@@ -0,0 +1,235 @@
+package imageutils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// ErrFramesTruncated dimensions are known, but data ended before last frame of animation.
+// Frame count and duration read so far are lower bounds
+var ErrFramesTruncated = fmt.Errorf("%w before last frame", ErrTruncated)
+
+// Animation frames of GIF, WebP or APNG image, still images have single frame
+type Animation struct {
+	Frames   int
+	Loops    int           // times animation is played, 0 forever
+	Duration time.Duration // single loop, sum of frame delays as stored in file
+
+	// GIF only, NETSCAPE2.0 or ANIMEXTS1.0 extension found before first image, marks
+	// animation even when data ends inside first frame
+	LoopExtension bool
+}
+
+// DecodeAnimation count frames of GIF, WebP, PNG or other format registered with Animation
+// decoder. ErrTruncated is returned together with frames read so far when body ends before
+// last frame
+func DecodeAnimation(body []byte) (Animation, error) {
+	f, ok := lookupFormat(body)
+	if !ok || f.Animation == nil {
+		return Animation{}, ErrFormat
+	}
+	return f.Animation(body)
+}
+
+// GIFAnimation walk blocks of GIF up to trailer, delays come from graphic control extensions
+// and loop count from NETSCAPE2.0 application extension
+func GIFAnimation(body []byte) (Animation, error) {
+	anim := Animation{Loops: 1}
+
+	if len(body) < 6 {
+		return anim, ErrTruncated
+	}
+	if string(body[:3]) != "GIF" {
+		return anim, ErrFormat
+	}
+	// logical screen descriptor with optional global color table
+	if len(body) < 13 {
+		return anim, ErrTruncated
+	}
+	i := 13
+	if body[10]&0x80 != 0 {
+		i += 3 << (body[10]&0x07 + 1)
+	}
+
+	var delay time.Duration
+	for {
+		if i >= len(body) {
+			return anim, ErrTruncated
+		}
+
+		switch body[i] {
+		case 0x21: // extension
+			if i+2 > len(body) {
+				return anim, ErrTruncated
+			}
+			label := body[i+1]
+			block := body[i+2:]
+
+			switch {
+			case label == 0xF9 && len(block) >= 5 && block[0] >= 4:
+				// graphic control extension, delay in 1/100 s applies to next image
+				delay = time.Duration(binary.LittleEndian.Uint16(block[2:4])) * 10 * time.Millisecond
+			case label == 0xFF && len(block) >= 16 && block[0] == 11 &&
+				(string(block[1:12]) == "NETSCAPE2.0" || string(block[1:12]) == "ANIMEXTS1.0") &&
+				block[12] >= 3 && block[13] == 1:
+				// loop count 0 is forever, otherwise number of repetitions
+				anim.LoopExtension = true
+				anim.Loops = int(binary.LittleEndian.Uint16(block[14:16]))
+				if anim.Loops > 0 {
+					anim.Loops++
+				}
+			}
+
+			next, err := skipSubBlocks(body, i+2)
+			if err != nil {
+				return anim, err
+			}
+			i = next
+
+		case 0x2C: // image descriptor
+			anim.Frames++
+			anim.Duration += delay
+			delay = 0
+
+			// position, size, flags with optional local color table, LZW code size
+			if i+10 > len(body) {
+				return anim, ErrTruncated
+			}
+			flags := body[i+9]
+			i += 10
+			if flags&0x80 != 0 {
+				i += 3 << (flags&0x07 + 1)
+			}
+			next, err := skipSubBlocks(body, i+1)
+			if err != nil {
+				return anim, err
+			}
+			i = next
+
+		case 0x3B: // trailer
+			return anim, nil
+
+		default:
+			return anim, ErrCorrupt
+		}
+	}
+}
+
+// skipSubBlocks offset after data sub-blocks starting at i, terminated by empty block
+func skipSubBlocks(body []byte, i int) (int, error) {
+	for {
+		if i >= len(body) {
+			return i, ErrTruncated
+		}
+		size := int(body[i])
+		i += 1 + size
+		if size == 0 {
+			return i, nil
+		}
+	}
+}
+
+// WEBPAnimation walk RIFF chunks of animated WebP, loop count comes from ANIM chunk
+// and frames with their durations from ANMF chunks
+func WEBPAnimation(body []byte) (Animation, error) {
+	anim := Animation{Loops: 1}
+
+	info, err := WEBPHeader(body)
+	if err != nil {
+		return anim, err
+	}
+	if !info.Animated {
+		anim.Frames = 1
+		return anim, nil
+	}
+
+	// RIFF size doesn't include first 8 bytes
+	end := int64(binary.LittleEndian.Uint32(body[4:8])) + 8
+	i := int64(12)
+	for i < end {
+		if i+8 > int64(len(body)) {
+			return anim, ErrTruncated
+		}
+		chunk := string(body[i : i+4])
+		size := int64(binary.LittleEndian.Uint32(body[i+4 : i+8]))
+		data := body[i+8 : min(i+8+size, int64(len(body)))]
+
+		switch {
+		case chunk == "ANIM" && len(data) >= 6:
+			anim.Loops = int(binary.LittleEndian.Uint16(data[4:6]))
+		case chunk == "ANMF" && len(data) >= 16:
+			// position, size and 24 bit duration in milliseconds
+			anim.Frames++
+			ms := uint32(data[12]) | uint32(data[13])<<8 | uint32(data[14])<<16
+			anim.Duration += time.Duration(ms) * time.Millisecond
+		}
+
+		// chunks are padded to even size
+		i += 8 + size + size&1
+	}
+
+	if end > int64(len(body)) {
+		return anim, ErrTruncated
+	}
+	return anim, nil
+}
+
+// PNGAnimation read acTL chunk of APNG and delays of its fcTL chunks, PNG without acTL
+// before image data is still
+func PNGAnimation(body []byte) (Animation, error) {
+	anim := Animation{Loops: 1}
+
+	if len(body) < 8 {
+		return anim, ErrTruncated
+	}
+	if !bytes.HasPrefix(body, pngSignature) {
+		return anim, ErrFormat
+	}
+
+	animated := false
+	i := int64(8)
+	for {
+		if i+8 > int64(len(body)) {
+			return anim, ErrTruncated
+		}
+		size := int64(binary.BigEndian.Uint32(body[i : i+4]))
+		chunk := string(body[i+4 : i+8])
+		data := body[i+8 : min(i+8+size, int64(len(body)))]
+
+		switch chunk {
+		case "acTL":
+			// number of frames and plays
+			if len(data) < 8 {
+				return anim, ErrTruncated
+			}
+			animated = true
+			anim.Frames = int(binary.BigEndian.Uint32(data[0:4]))
+			anim.Loops = int(binary.BigEndian.Uint32(data[4:8]))
+		case "fcTL":
+			// sequence, size, offset, then delay as fraction of second
+			if animated && len(data) >= 24 {
+				num := time.Duration(binary.BigEndian.Uint16(data[20:22]))
+				den := time.Duration(binary.BigEndian.Uint16(data[22:24]))
+				if den == 0 {
+					den = 100
+				}
+				anim.Duration += num * time.Second / den
+			}
+		case "IDAT":
+			if !animated {
+				anim.Frames = 1
+				return anim, nil
+			}
+		case "IEND":
+			if !animated {
+				anim.Frames = 1
+			}
+			return anim, nil
+		}
+
+		// length, type, data and CRC
+		i += 12 + size
+	}
+}
@@ -0,0 +1,144 @@
+package imageutils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+// animatedWebP canvas 64x48 with two ANMF frames of 100 and 150 ms, played twice
+func animatedWebP() []byte {
+	vp8x := []byte{0x02, 0, 0, 0, 63, 0, 0, 47, 0, 0}
+	anim := []byte{0, 0, 0, 0, 2, 0}
+	body := webpChunk("VP8X", vp8x)
+	for _, chunk := range [][]byte{
+		append([]byte("ANIM"), append(le32(uint32(len(anim))), anim...)...),
+		append([]byte("ANMF"), append(le32(17), 0, 0, 0, 0, 0, 0, 63, 0, 0, 47, 0, 0, 100, 0, 0, 0, 0xAA, 0)...),
+		append([]byte("ANMF"), append(le32(16), 0, 0, 0, 0, 0, 0, 63, 0, 0, 47, 0, 0, 150, 0, 0, 0)...),
+	} {
+		body = append(body, chunk...)
+	}
+	binary.LittleEndian.PutUint32(body[4:], uint32(len(body)-8))
+	return body
+}
+
+func TestDecodeAnimation(t *testing.T) {
+	var samples = []struct {
+		src  string
+		want Animation
+	}{
+		{"samples/file.gif", Animation{Frames: 1, Loops: 1}},
+		{"samples/file2.gif", Animation{Frames: 3, Loops: 0, Duration: 300 * time.Millisecond, LoopExtension: true}},
+		{"samples/file.png", Animation{Frames: 1, Loops: 1}},
+		{"samples/file2.png", Animation{Frames: 2, Loops: 3, Duration: 400 * time.Millisecond}},
+		{"samples/file.webp", Animation{Frames: 1, Loops: 1}},
+	}
+
+	for _, sample := range samples {
+		data, err := ioutil.ReadFile(sample.src)
+		check(err)
+		if anim, err := DecodeAnimation(data); err != nil || anim != sample.want {
+			t.Errorf("DecodeAnimation (%s) returned %+v, %v, expected %+v", sample.src, anim, err, sample.want)
+		}
+	}
+
+	want := Animation{Frames: 2, Loops: 2, Duration: 250 * time.Millisecond}
+	if anim, err := DecodeAnimation(animatedWebP()); err != nil || anim != want {
+		t.Errorf("DecodeAnimation (animated webp) returned %+v, %v, expected %+v", anim, err, want)
+	}
+
+	if _, err := DecodeAnimation([]byte("BM")); !errors.Is(err, ErrFormat) {
+		t.Errorf("DecodeAnimation of bmp returned %v, expected %v", err, ErrFormat)
+	}
+}
+
+func TestDecodeAnimationTruncated(t *testing.T) {
+	data, err := ioutil.ReadFile("samples/file2.gif")
+	check(err)
+
+	// frames read so far are lower bound
+	anim, err := GIFAnimation(data[:len(data)/2])
+	if !errors.Is(err, ErrTruncated) || anim.Frames < 1 || anim.Frames > 2 {
+		t.Errorf("GIFAnimation of half file returned %+v, %v", anim, err)
+	}
+
+	webp := animatedWebP()
+	anim, err = WEBPAnimation(webp[:len(webp)-20])
+	if !errors.Is(err, ErrTruncated) || anim.Frames != 1 || anim.Loops != 2 {
+		t.Errorf("WEBPAnimation of truncated file returned %+v, %v", anim, err)
+	}
+
+	// acTL is before image data, duration comes from all fcTL chunks
+	data, err = ioutil.ReadFile("samples/file2.png")
+	check(err)
+	anim, err = PNGAnimation(data[:100])
+	if !errors.Is(err, ErrTruncated) || anim.Frames != 2 || anim.Loops != 3 || anim.Duration != 200*time.Millisecond {
+		t.Errorf("PNGAnimation of truncated file returned %+v, %v", anim, err)
+	}
+
+	for _, data := range readSamples(t, "samples/*.gif", "samples/*.png", "samples/*.webp") {
+		for n := 16; n < len(data); n++ {
+			if _, err := DecodeAnimation(data[:n]); err != nil && !errors.Is(err, ErrTruncated) {
+				t.Fatalf("DecodeAnimation of %d bytes returned %v", n, err)
+			}
+		}
+	}
+}
+
+func TestHeaderReaderFrames(t *testing.T) {
+	data, err := ioutil.ReadFile("samples/file2.gif")
+	check(err)
+
+	h := HeaderReader{Frames: true}
+	header, err := h.Decode(bytes.NewReader(data))
+	want := Header{Type: "gif", Width: 32, Height: 24, Animated: true, Frames: 3, Duration: 300 * time.Millisecond}
+	if err != nil || header != want || h.Len() != len(data) {
+		t.Errorf("Decode (samples/file2.gif) returned %+v, %v after %d bytes, expected %+v", header, err, h.Len(), want)
+	}
+
+	// dimensions are known when limit is reached before last frame
+	h = HeaderReader{Limit: len(data) / 2, Frames: true}
+	header, err = h.Decode(bytes.NewReader(data))
+	if !errors.Is(err, ErrFramesTruncated) || !errors.Is(err, ErrTruncated) || header.Width != 32 || header.Height != 24 {
+		t.Errorf("Decode (samples/file2.gif) with limit returned %+v, %v", header, err)
+	}
+
+	// limit inside first frame, loop extension before it marks animation
+	first := bytes.Index(data, []byte{0x2C, 0, 0, 0, 0})
+	h = HeaderReader{Limit: first + 20, Frames: true}
+	header, err = h.Decode(bytes.NewReader(data))
+	want = Header{Type: "gif", Width: 32, Height: 24, Animated: true, Frames: 1, Duration: 100 * time.Millisecond}
+	if !errors.Is(err, ErrFramesTruncated) || header != want {
+		t.Errorf("Decode (samples/file2.gif) with limit inside first frame returned %+v, %v, expected %+v", header, err, want)
+	}
+	if header, err := DecodeHeader(data[:first+20]); err != nil || !header.Animated {
+		t.Errorf("DecodeHeader (samples/file2.gif) inside first frame returned %+v, %v", header, err)
+	}
+
+	// still images stop at image data
+	data, err = ioutil.ReadFile("samples/file.png")
+	check(err)
+	h = HeaderReader{Frames: true}
+	if header, err := h.Decode(bytes.NewReader(data)); err != nil || header.Animated || h.Len() >= len(data) {
+		t.Errorf("Decode (samples/file.png) returned %+v, %v after %d bytes", header, err, h.Len())
+	}
+}
+
+func FuzzDecodeAnimation(f *testing.F) {
+	for _, data := range readSamples(f, "samples/*.gif", "samples/*.png", "samples/*.webp") {
+		f.Add(data)
+	}
+	f.Add(animatedWebP())
+	f.Fuzz(func(t *testing.T, data []byte) {
+		anim, err := DecodeAnimation(data)
+		if err != nil && !errors.Is(err, ErrFormat) && !errors.Is(err, ErrTruncated) && !errors.Is(err, ErrCorrupt) {
+			t.Errorf("DecodeAnimation returned unexpected error %v", err)
+		}
+		if anim.Frames < 0 || anim.Duration < 0 {
+			t.Errorf("DecodeAnimation returned %+v", anim)
+		}
+	})
+}
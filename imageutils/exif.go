@@ -0,0 +1,184 @@
+package imageutils
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// TIFF and EXIF tags used by parsers
+const (
+	tagImageWidth       = 0x0100
+	tagImageLength      = 0x0101
+	tagBitsPerSample    = 0x0102
+	tagPhotometric      = 0x0106
+	tagMake             = 0x010F
+	tagModel            = 0x0110
+	tagOrientation      = 0x0112
+	tagSamplesPerPixel  = 0x0115
+	tagDateTime         = 0x0132
+	tagExtraSamples     = 0x0152
+	tagExifIFD          = 0x8769
+	tagICCProfile       = 0x8773
+	tagDateTimeOriginal = 0x9003
+)
+
+// tiffTypeSizes bytes per value of field types
+var tiffTypeSizes = map[uint16]int64{
+	1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8,
+}
+
+// tiffReader reads IFDs of TIFF file or EXIF block
+type tiffReader struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+// ifdEntry single field of IFD, value holds 4 raw bytes of value or offset
+type ifdEntry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+// newTIFFReader read TIFF header, returns reader and offset of first IFD
+func newTIFFReader(data []byte) (*tiffReader, uint32, error) {
+	if len(data) < 8 {
+		return nil, 0, ErrTruncated
+	}
+
+	t := &tiffReader{data: data}
+	switch string(data[0:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return nil, 0, ErrFormat
+	}
+	if t.order.Uint16(data[2:4]) != 42 {
+		return nil, 0, ErrFormat
+	}
+
+	offset := t.order.Uint32(data[4:8])
+	if offset < 8 {
+		return nil, 0, ErrCorrupt
+	}
+	return t, offset, nil
+}
+
+// entries of IFD at offset, ErrTruncated when IFD is not complete
+func (t *tiffReader) entries(offset uint32) ([]ifdEntry, error) {
+	if int64(offset)+2 > int64(len(t.data)) {
+		return nil, ErrTruncated
+	}
+	count := int64(t.order.Uint16(t.data[offset : offset+2]))
+	if int64(offset)+2+count*12 > int64(len(t.data)) {
+		return nil, ErrTruncated
+	}
+
+	entries := make([]ifdEntry, count)
+	for i := range entries {
+		e := t.data[int64(offset)+2+int64(i)*12:]
+		entries[i] = ifdEntry{
+			tag:   t.order.Uint16(e[0:2]),
+			typ:   t.order.Uint16(e[2:4]),
+			count: t.order.Uint32(e[4:8]),
+			value: e[8:12],
+		}
+	}
+	return entries, nil
+}
+
+// uint value of SHORT or LONG entry
+func (t *tiffReader) uint(e ifdEntry) (uint32, bool) {
+	switch e.typ {
+	case 3: // SHORT
+		return uint32(t.order.Uint16(e.value)), true
+	case 4: // LONG
+		return t.order.Uint32(e.value), true
+	}
+	return 0, false
+}
+
+// bytes of entry value, stored in entry itself when it fits 4 bytes, otherwise at offset
+func (t *tiffReader) bytes(e ifdEntry) ([]byte, bool) {
+	size, ok := tiffTypeSizes[e.typ]
+	if !ok {
+		return nil, false
+	}
+	size *= int64(e.count)
+	if size <= 4 {
+		return e.value[:size], true
+	}
+	offset := int64(t.order.Uint32(e.value))
+	if offset+size > int64(len(t.data)) {
+		return nil, false
+	}
+	return t.data[offset : offset+size], true
+}
+
+// string value of ASCII entry, up to first NUL
+func (t *tiffReader) string(e ifdEntry) string {
+	if e.typ != 2 {
+		return ""
+	}
+	b, _ := t.bytes(e)
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(bytes.TrimSpace(b))
+}
+
+// find entry with tag
+func findEntry(entries []ifdEntry, tag uint16) (ifdEntry, bool) {
+	for _, e := range entries {
+		if e.tag == tag {
+			return e, true
+		}
+	}
+	return ifdEntry{}, false
+}
+
+// exifOrientation orientation tag of EXIF block without "Exif\0\0" prefix, 1 when missing
+func exifOrientation(exif []byte) int {
+	t, offset, err := newTIFFReader(exif)
+	if err != nil {
+		return 1
+	}
+	entries, err := t.entries(offset)
+	if err != nil {
+		return 1
+	}
+	if e, ok := findEntry(entries, tagOrientation); ok {
+		if v, ok := t.uint(e); ok && v >= 1 && v <= 8 {
+			return int(v)
+		}
+	}
+	return 1
+}
+
+// camera make, model and date of original from IFD0 and EXIF sub-IFD, date
+// falls back to modification date
+func (t *tiffReader) camera(entries []ifdEntry) (maker, model, date string) {
+	if e, ok := findEntry(entries, tagMake); ok {
+		maker = t.string(e)
+	}
+	if e, ok := findEntry(entries, tagModel); ok {
+		model = t.string(e)
+	}
+	if e, ok := findEntry(entries, tagDateTime); ok {
+		date = t.string(e)
+	}
+
+	if e, ok := findEntry(entries, tagExifIFD); ok {
+		if offset, ok := t.uint(e); ok {
+			if exif, err := t.entries(offset); err == nil {
+				if e, ok := findEntry(exif, tagDateTimeOriginal); ok && t.string(e) != "" {
+					date = t.string(e)
+				}
+			}
+		}
+	}
+	return maker, model, date
+}
@@ -0,0 +1,241 @@
+package imageutils
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// BMPDimensions returns dimensions of BMP image, both OS/2 and Windows headers,
+// bottom-up and top-down
+func BMPDimensions(body []byte) (int32, int32, error) {
+	if len(body) < 2 {
+		return 0, 0, ErrTruncated
+	}
+	if body[0] != 'B' || body[1] != 'M' {
+		return 0, 0, ErrFormat
+	}
+	if len(body) < 22 {
+		return 0, 0, ErrTruncated
+	}
+
+	// OS/2 BITMAPCOREHEADER has 16 bit sizes
+	if binary.LittleEndian.Uint32(body[14:18]) == 12 {
+		return int32(binary.LittleEndian.Uint16(body[18:20])), int32(binary.LittleEndian.Uint16(body[20:22])), nil
+	}
+	if len(body) < 26 {
+		return 0, 0, ErrTruncated
+	}
+
+	width := int64(int32(binary.LittleEndian.Uint32(body[18:22])))
+	height := int64(int32(binary.LittleEndian.Uint32(body[22:26])))
+	// negative height means top-down image
+	if height < 0 {
+		height = -height
+	}
+	return checkSize(width, height)
+}
+
+// TIFFDimensions returns dimensions of first image of TIFF file, ErrTruncated when first IFD
+// lies outside of body
+func TIFFDimensions(body []byte) (int32, int32, error) {
+	t, offset, err := newTIFFReader(body)
+	if err != nil {
+		return 0, 0, err
+	}
+	entries, err := t.entries(offset)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var width, height uint32
+	if e, ok := findEntry(entries, tagImageWidth); ok {
+		width, _ = t.uint(e)
+	}
+	if e, ok := findEntry(entries, tagImageLength); ok {
+		height, _ = t.uint(e)
+	}
+
+	if width == 0 || height == 0 {
+		return 0, 0, ErrCorrupt
+	}
+	return checkSize(int64(width), int64(height))
+}
+
+// ICODimensions returns dimensions of largest image in ICO or CUR file, PNG
+// entries are measured from their own header
+func ICODimensions(body []byte) (int32, int32, error) {
+	w, h, _, err := largestICOEntry(body)
+	return w, h, err
+}
+
+// largestICOEntry dimensions and directory entry of largest image in ICO or CUR file
+func largestICOEntry(body []byte) (int32, int32, []byte, error) {
+	if len(body) < 6 {
+		return 0, 0, nil, ErrTruncated
+	}
+	if body[0] != 0 || body[1] != 0 || (body[2] != 1 && body[2] != 2) || body[3] != 0 {
+		return 0, 0, nil, ErrFormat
+	}
+	count := int(binary.LittleEndian.Uint16(body[4:6]))
+	if count == 0 {
+		return 0, 0, nil, ErrCorrupt
+	}
+
+	var width, height int32
+	var largest []byte
+	for i := 0; i < count; i++ {
+		entry := 6 + i*16
+		if entry+16 > len(body) {
+			if width == 0 {
+				return 0, 0, nil, ErrTruncated
+			}
+			break
+		}
+
+		// 0 stands for 256
+		w, h := int32(body[entry]), int32(body[entry+1])
+		if w == 0 {
+			w = 256
+		}
+		if h == 0 {
+			h = 256
+		}
+
+		offset := int64(binary.LittleEndian.Uint32(body[entry+12 : entry+16]))
+		if offset+24 <= int64(len(body)) && bytes.HasPrefix(body[offset:], pngSignature) {
+			if pw, ph, err := PNGDimensions(body[offset:]); err == nil {
+				w, h = pw, ph
+			}
+		}
+
+		if int64(w)*int64(h) > int64(width)*int64(height) {
+			width, height, largest = w, h, body[entry:entry+16]
+		}
+	}
+
+	return width, height, largest, nil
+}
+
+var (
+	pngSignature       = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1A, '\n'}
+	jxlSignature       = []byte{0xFF, 0x0A}
+	jxlContainerHeader = []byte{0, 0, 0, 0x0C, 'J', 'X', 'L', ' ', '\r', '\n', 0x87, '\n'}
+)
+
+// JXLDimensions returns dimensions of JPEG XL image, bare codestream or ISO-BMFF container,
+// orientation from image metadata is applied
+func JXLDimensions(body []byte) (int32, int32, error) {
+	codestream, err := jxlCodestream(body)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	r := bitReader{data: codestream[2:]}
+	width, height := jxlSize(&r)
+
+	// image metadata: all_default, extra_fields, orientation
+	if r.read(1) == 0 && r.read(1) == 1 {
+		if orientation := 1 + r.read(3); orientation > 4 {
+			width, height = height, width
+		}
+	}
+
+	if r.short {
+		return 0, 0, ErrTruncated
+	}
+	return checkSize(int64(width), int64(height))
+}
+
+// jxlCodestream bare codestream or first codestream box of container, starting with signature
+func jxlCodestream(body []byte) ([]byte, error) {
+	if len(body) < 2 {
+		return nil, ErrTruncated
+	}
+	if bytes.HasPrefix(body, jxlContainerHeader) {
+		codestream := []byte(nil)
+		boxes, _ := readBoxes(body)
+		for _, b := range boxes {
+			if b.typ == "jxlc" {
+				codestream = b.data
+				break
+			}
+			// partial codestream boxes have 4 byte index
+			if b.typ == "jxlp" && len(b.data) >= 4 {
+				codestream = b.data[4:]
+				break
+			}
+		}
+		if codestream == nil {
+			return nil, ErrTruncated
+		}
+		body = codestream
+	}
+
+	if len(body) < 2 {
+		return nil, ErrTruncated
+	}
+	if !bytes.HasPrefix(body, jxlSignature) {
+		return nil, ErrFormat
+	}
+	return body, nil
+}
+
+// jxlSize SizeHeader of codestream, also used for intrinsic size in image metadata
+func jxlSize(r *bitReader) (uint64, uint64) {
+	var width, height uint64
+
+	div8 := r.read(1) == 1
+	if div8 {
+		height = 8 * (1 + uint64(r.read(5)))
+	} else {
+		height = 1 + uint64(r.u32(9, 13, 18, 30))
+	}
+
+	ratio := r.read(3)
+	switch ratio {
+	case 0:
+		if div8 {
+			width = 8 * (1 + uint64(r.read(5)))
+		} else {
+			width = 1 + uint64(r.u32(9, 13, 18, 30))
+		}
+	default:
+		num := [...]uint64{0, 1, 12, 4, 3, 16, 5, 2}
+		den := [...]uint64{0, 1, 10, 3, 2, 9, 4, 1}
+		width = height * num[ratio] / den[ratio]
+	}
+
+	return width, height
+}
+
+// bitReader least significant bit first reader used by JPEG XL headers
+type bitReader struct {
+	data  []byte
+	pos   int
+	short bool
+}
+
+// read n bits, short is set when data ends
+func (r *bitReader) read(n int) uint32 {
+	var v uint32
+	for i := 0; i < n; i++ {
+		if r.pos/8 >= len(r.data) {
+			r.short = true
+			return 0
+		}
+		v |= uint32(r.data[r.pos/8]>>(r.pos%8)&1) << i
+		r.pos++
+	}
+	return v
+}
+
+// u32 value with 2 bit selector choosing one of bit widths
+func (r *bitReader) u32(bits ...int) uint32 {
+	return r.read(bits[r.read(2)])
+}
+
+// u32Dist value with 2 bit selector choosing one of distributions, each is {offset, bits}
+func (r *bitReader) u32Dist(dist [4][2]uint32) uint32 {
+	d := dist[r.read(2)]
+	return d[0] + r.read(int(d[1]))
+}
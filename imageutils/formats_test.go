@@ -0,0 +1,50 @@
+package imageutils
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func TestFormatDimensions(t *testing.T) {
+	var samples = []struct {
+		src   string
+		parse func([]byte) (int32, int32, error)
+		w, h  int32
+	}{
+		{"samples/file.bmp", BMPDimensions, 150, 103},
+		{"samples/file2.bmp", BMPDimensions, 16, 12},    // BITMAPV5HEADER
+		{"samples/file.tiff", TIFFDimensions, 150, 103}, // big endian
+		{"samples/file2.tiff", TIFFDimensions, 153, 55}, // little endian
+		{"samples/file.ico", ICODimensions, 48, 48},     // largest entry is PNG
+		{"samples/file.cur", ICODimensions, 32, 32},
+		{"samples/file.jxl", JXLDimensions, 512, 512},  // bare codestream
+		{"samples/file2.jxl", JXLDimensions, 512, 512}, // ISO-BMFF container
+	}
+
+	for _, sample := range samples {
+		data, err := ioutil.ReadFile(sample.src)
+		check(err)
+		if w, h, err := sample.parse(data); err != nil || w != sample.w || h != sample.h {
+			t.Errorf("dimensions of %s returned (%d, %d), expected %d, %d", sample.src, w, h, sample.w, sample.h)
+		}
+
+	}
+}
+
+func TestJXLDimensionsRatio(t *testing.T) {
+	// div8 height 8*(1+29), ratio 7 (2:1)
+	var samples = []struct {
+		name string
+		data []byte
+		w, h int32
+	}{
+		{"default metadata", []byte{0xFF, 0x0A, 0xFB, 0x03}, 480, 240},
+		{"orientation 6", []byte{0xFF, 0x0A, 0xFB, 0x2D}, 240, 480},
+	}
+
+	for _, sample := range samples {
+		if w, h, err := JXLDimensions(sample.data); err != nil || w != sample.w || h != sample.h {
+			t.Errorf("JXLDimensions (%s) returned (%d, %d, %v), expected %d, %d", sample.name, w, h, err, sample.w, sample.h)
+		}
+	}
+}
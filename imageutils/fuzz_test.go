@@ -0,0 +1,142 @@
+package imageutils
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+type dimensionsFunc func([]byte) (int32, int32, error)
+
+var parsers = []struct {
+	samples []string
+	parse   dimensionsFunc
+}{
+	{[]string{"samples/*.png"}, PNGDimensions},
+	{[]string{"samples/*.gif"}, GIFDimensions},
+	{[]string{"samples/*.jpg"}, JPGDimensions},
+	{[]string{"samples/*.webp"}, WEBPDimensions},
+	{[]string{"samples/*.svg"}, SVGDimensions},
+	{[]string{"samples/*.avif", "samples/*.heic"}, HEIFDimensions},
+	{[]string{"samples/*.bmp"}, BMPDimensions},
+	{[]string{"samples/*.tiff"}, TIFFDimensions},
+	{[]string{"samples/*.ico", "samples/*.cur"}, ICODimensions},
+	{[]string{"samples/*.jxl"}, JXLDimensions},
+}
+
+// readSamples read files matching patterns, big files are cut to header size
+func readSamples(t testing.TB, patterns ...string) [][]byte {
+	files := make([]string, 0)
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, matches...)
+	}
+
+	samples := make([][]byte, 0, len(files))
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) > 64*1024 {
+			data = data[:64*1024]
+		}
+		samples = append(samples, data)
+	}
+	return samples
+}
+
+// checkDimensions parser must not panic and must not return negative sizes
+func checkDimensions(t *testing.T, parse dimensionsFunc, data []byte) {
+	w, h, err := parse(data)
+	if err == nil && (w < 0 || h < 0) {
+		t.Errorf("returned negative size (%d, %d) for %x", w, h, data)
+	}
+	if err != nil && (w != 0 || h != 0) {
+		t.Errorf("returned size (%d, %d) with error %v", w, h, err)
+	}
+}
+
+func TestTruncatedImages(t *testing.T) {
+	for _, p := range parsers {
+		for _, data := range readSamples(t, p.samples...) {
+			for i := 0; i < len(data) && i < 2048; i++ {
+				checkDimensions(t, p.parse, data[:i])
+			}
+		}
+	}
+
+	var samples = []struct {
+		name  string
+		parse dimensionsFunc
+		data  []byte
+		err   error
+	}{
+		{"short png", PNGDimensions, []byte("\x89PNG\r\n\x1a\n\x00\x00"), ErrTruncated},
+		{"jpg without frame", JPGHeaders, []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00}, ErrTruncated},
+		{"jpg cut in frame", JPGHeaders, []byte{0xFF, 0xD8, 0xFF, 0xC0, 0x00, 0x11}, ErrTruncated},
+		{"short webp", WEBPDimensions, []byte("RIFF\x00\x00\x00\x00WEBPVP8X"), ErrTruncated},
+		{"png as gif", GIFDimensions, []byte("\x89PNG\r\n\x1a\n\x00\x00"), ErrFormat},
+		{"tiff ifd beyond body", TIFFDimensions, []byte("II*\x00\x00\x10\x00\x00"), ErrTruncated},
+		{"bmp negative width", BMPDimensions, []byte("BM\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x28\x00\x00\x00\xff\xff\xff\xff\x01\x00\x00\x00"), ErrCorrupt},
+	}
+
+	for _, sample := range samples {
+		if _, _, err := sample.parse(sample.data); !errors.Is(err, sample.err) {
+			t.Errorf("%s returned %v, expected %v", sample.name, err, sample.err)
+		}
+	}
+}
+
+func fuzzDimensions(f *testing.F, parse dimensionsFunc, patterns ...string) {
+	for _, data := range readSamples(f, patterns...) {
+		f.Add(data)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		checkDimensions(t, parse, data)
+	})
+}
+
+func FuzzPNGDimensions(f *testing.F) {
+	fuzzDimensions(f, PNGDimensions, "samples/*.png")
+}
+
+func FuzzGIFDimensions(f *testing.F) {
+	fuzzDimensions(f, GIFDimensions, "samples/*.gif")
+}
+
+func FuzzJPGDimensions(f *testing.F) {
+	fuzzDimensions(f, JPGDimensions, "samples/*.jpg")
+}
+
+func FuzzWEBPDimensions(f *testing.F) {
+	fuzzDimensions(f, WEBPDimensions, "samples/*.webp")
+}
+
+func FuzzSVGDimensions(f *testing.F) {
+	fuzzDimensions(f, SVGDimensions, "samples/*.svg")
+}
+
+func FuzzHEIFDimensions(f *testing.F) {
+	fuzzDimensions(f, HEIFDimensions, "samples/*.avif", "samples/*.heic")
+}
+
+func FuzzBMPDimensions(f *testing.F) {
+	fuzzDimensions(f, BMPDimensions, "samples/*.bmp")
+}
+
+func FuzzTIFFDimensions(f *testing.F) {
+	fuzzDimensions(f, TIFFDimensions, "samples/*.tiff")
+}
+
+func FuzzICODimensions(f *testing.F) {
+	fuzzDimensions(f, ICODimensions, "samples/*.ico", "samples/*.cur")
+}
+
+func FuzzJXLDimensions(f *testing.F) {
+	fuzzDimensions(f, JXLDimensions, "samples/*.jxl")
+}
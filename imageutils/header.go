@@ -0,0 +1,114 @@
+package imageutils
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"time"
+)
+
+// Header dimensions and basic properties read from image header
+type Header struct {
+	Type     string
+	Width    int32
+	Height   int32
+	Animated bool
+	Alpha    bool
+	Vector   bool // scales to any size, dimensions are intrinsic size if known
+
+	// animations only, counted from available data
+	Frames   int
+	Loops    int // times animation is played, 0 forever
+	Duration time.Duration
+}
+
+// minDetectBytes bytes needed before unknown data is reported as ErrFormat, SVG
+// prolog might be longer
+const minDetectBytes = 512
+
+// DecodeHeader detect type of image by registered formats and read its dimensions. ErrTruncated means body ends
+// before dimensions, more data of same image might be decoded later. ErrNoIntrinsicSize
+// is returned for vector images without size
+func DecodeHeader(body []byte) (Header, error) {
+	f, ok := lookupFormat(body)
+	if !ok {
+		if len(body) < minDetectBytes || partialSVG(bytes.TrimRight(body, "\x00")) {
+			return Header{}, ErrTruncated
+		}
+		return Header{}, ErrFormat
+	}
+
+	header, err := f.Decode(body)
+	header.Type = f.Name
+
+	// loop extension says little about complete file, single frame GIF may have it too
+	if err == nil && f.Animation != nil {
+		if anim, animErr := f.Animation(body); anim.Frames > 1 || (anim.LoopExtension && errors.Is(animErr, ErrTruncated)) {
+			header.Animated = true
+			header.Frames, header.Loops, header.Duration = anim.Frames, anim.Loops, anim.Duration
+		}
+	}
+
+	return header, err
+}
+
+// HeaderReader reads image header incrementally, possibly from several readers
+// like follow-up range requests, and stops as soon as dimensions are known
+type HeaderReader struct {
+	Limit  int  // max bytes to buffer, 0 means no limit
+	Frames bool // keep reading animated formats like GIF, WebP and PNG until all frames are counted
+	buf    []byte
+}
+
+// headerChunk size of single read
+const headerChunk = 4096
+
+// Len bytes read so far, offset where next range should start
+func (h *HeaderReader) Len() int {
+	return len(h.buf)
+}
+
+// Bytes read so far
+func (h *HeaderReader) Bytes() []byte {
+	return h.buf
+}
+
+// Decode read r until header can be decoded. ErrTruncated is returned when r or Limit
+// ended before, caller can continue with reader of next bytes. With Frames set, dimensions
+// of animation are returned with ErrFramesTruncated when its last frame wasn't reached
+func (h *HeaderReader) Decode(r io.Reader) (Header, error) {
+	chunk := make([]byte, headerChunk)
+	header, decodeErr := Header{}, ErrTruncated
+
+	for {
+		size := len(chunk)
+		if h.Limit > 0 {
+			if len(h.buf) >= h.Limit {
+				return header, decodeErr
+			}
+			size = min(size, h.Limit-len(h.buf))
+		}
+
+		n, err := r.Read(chunk[:size])
+		if n > 0 {
+			h.buf = append(h.buf, chunk[:n]...)
+
+			header, decodeErr = DecodeHeader(h.buf)
+			if decodeErr == nil && h.Frames {
+				if _, animErr := DecodeAnimation(h.buf); errors.Is(animErr, ErrTruncated) {
+					decodeErr = ErrFramesTruncated
+				}
+			}
+			if !errors.Is(decodeErr, ErrTruncated) {
+				return header, decodeErr
+			}
+		}
+
+		if err == io.EOF {
+			return header, decodeErr
+		}
+		if err != nil {
+			return header, err
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package imageutils
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestDecodeHeader(t *testing.T) {
+	var samples = []struct {
+		src  string
+		want Header
+	}{
+		{"samples/file.png", Header{Type: "png", Width: 521, Height: 450}},
+		{"samples/file.webp", Header{Type: "webp", Width: 521, Height: 450, Alpha: true}},
+		{"samples/file4.jpg", Header{Type: "jpg", Width: 5616, Height: 3744}},
+		{"samples/file.tiff", Header{Type: "tiff", Width: 150, Height: 103}},
+		{"samples/file2.png", Header{Type: "png", Width: 32, Height: 24, Animated: true, Frames: 2, Loops: 3, Duration: 400 * time.Millisecond}},
+	}
+
+	for _, sample := range samples {
+		data, err := ioutil.ReadFile(sample.src)
+		check(err)
+		if header, err := DecodeHeader(data); err != nil || header != sample.want {
+			t.Errorf("DecodeHeader (%s) returned %+v, %v, expected %+v", sample.src, header, err, sample.want)
+		}
+	}
+
+	if _, err := DecodeHeader([]byte("<html>")); !errors.Is(err, ErrTruncated) {
+		t.Errorf("DecodeHeader of short unknown data returned %v, expected %v", err, ErrTruncated)
+	}
+	if _, err := DecodeHeader(bytes.Repeat([]byte("<html>"), 100)); !errors.Is(err, ErrFormat) {
+		t.Errorf("DecodeHeader of unknown data returned %v, expected %v", err, ErrFormat)
+	}
+}
+
+func TestHeaderReader(t *testing.T) {
+	data, err := ioutil.ReadFile("samples/file.gif")
+	check(err)
+
+	// stops right after header, even when reader returns single bytes
+	var h HeaderReader
+	header, err := h.Decode(iotest.OneByteReader(bytes.NewReader(data)))
+	if err != nil || header.Width != 251 || header.Height != 201 || h.Len() != 10 {
+		t.Errorf("Decode (samples/file.gif) returned %+v, %v after %d bytes", header, err, h.Len())
+	}
+
+	// frame header of file4.jpg lies behind 4kB EXIF, continue with next range
+	data, err = ioutil.ReadFile("samples/file4.jpg")
+	check(err)
+	h = HeaderReader{Limit: 51200}
+	if _, err := h.Decode(bytes.NewReader(data[:4096])); !errors.Is(err, ErrTruncated) || h.Len() != 4096 {
+		t.Errorf("Decode first range returned %v after %d bytes, expected %v", err, h.Len(), ErrTruncated)
+	}
+	header, err = h.Decode(bytes.NewReader(data[h.Len():]))
+	if err != nil || header.Width != 5616 || header.Height != 3744 || h.Len() >= 51200 {
+		t.Errorf("Decode second range returned %+v, %v after %d bytes", header, err, h.Len())
+	}
+
+	// limit reached
+	h = HeaderReader{Limit: 1000}
+	if _, err := h.Decode(bytes.NewReader(data)); !errors.Is(err, ErrTruncated) || h.Len() != 1000 {
+		t.Errorf("Decode over limit returned %v after %d bytes, expected %v", err, h.Len(), ErrTruncated)
+	}
+}
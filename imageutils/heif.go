@@ -0,0 +1,255 @@
+package imageutils
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// box ISO-BMFF box, data is content without header
+type box struct {
+	typ  string
+	data []byte
+}
+
+// readBoxes split ISO-BMFF data into boxes, truncated last box keeps what is available
+// and truncated is set
+func readBoxes(data []byte) (boxes []box, truncated bool) {
+	boxes = make([]box, 0)
+
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return boxes, true
+		}
+		size := uint64(binary.BigEndian.Uint32(data[0:4]))
+		typ := string(data[4:8])
+		header := uint64(8)
+
+		switch size {
+		case 0:
+			size = uint64(len(data))
+		case 1:
+			if len(data) < 16 {
+				return boxes, true
+			}
+			size = binary.BigEndian.Uint64(data[8:16])
+			header = 16
+		}
+		if size < header {
+			return boxes, false
+		}
+		if size > uint64(len(data)) {
+			size = uint64(len(data))
+			truncated = true
+		}
+
+		boxes = append(boxes, box{typ: typ, data: data[header:size]})
+		data = data[size:]
+	}
+
+	return boxes, truncated
+}
+
+// findBox first box of given type
+func findBox(boxes []box, typ string) (box, bool) {
+	for _, b := range boxes {
+		if b.typ == typ {
+			return b, true
+		}
+	}
+	return box{}, false
+}
+
+// heifBrand image type by ftyp brands, avif, heic or empty when file is not HEIF
+func heifBrand(data []byte) string {
+	if len(data) < 12 || string(data[4:8]) != "ftyp" {
+		return ""
+	}
+
+	size := int(binary.BigEndian.Uint32(data[0:4]))
+	if size < 16 || size > len(data) {
+		size = len(data)
+	}
+
+	// major brand, minor version, compatible brands
+	brands := []string{string(data[8:12])}
+	for i := 16; i+4 <= size; i += 4 {
+		brands = append(brands, string(data[i:i+4]))
+	}
+
+	heic := false
+	for _, brand := range brands {
+		switch brand {
+		case "avif", "avis":
+			return "avif"
+		case "heic", "heix", "heim", "heis", "hevc", "hevx", "mif1", "msf1":
+			heic = true
+		}
+	}
+	if heic {
+		return "heic"
+	}
+	return ""
+}
+
+// HEIFDimensions returns display width and height of AVIF or HEIC image, read from ispe
+// property of primary item with irot rotation and clap crop applied
+func HEIFDimensions(body []byte) (int32, int32, error) {
+	if heifBrand(body) == "" {
+		if len(body) < 12 {
+			return 0, 0, ErrTruncated
+		}
+		return 0, 0, ErrFormat
+	}
+
+	// missing box is reported as truncation when data ended early
+	missing := func(truncated bool) (int32, int32, error) {
+		if truncated {
+			return 0, 0, ErrTruncated
+		}
+		return 0, 0, ErrCorrupt
+	}
+
+	top, truncated := readBoxes(body)
+	meta, ok := findBox(top, "meta")
+	if !ok || len(meta.data) < 4 {
+		return missing(truncated)
+	}
+	children, metaTruncated := readBoxes(meta.data[4:])
+	truncated = truncated || metaTruncated
+
+	primary, hasPrimary := uint32(0), false
+	if pitm, ok := findBox(children, "pitm"); ok && len(pitm.data) >= 6 {
+		if pitm.data[0] == 0 {
+			primary, hasPrimary = uint32(binary.BigEndian.Uint16(pitm.data[4:6])), true
+		} else if len(pitm.data) >= 8 {
+			primary, hasPrimary = binary.BigEndian.Uint32(pitm.data[4:8]), true
+		}
+	}
+
+	iprp, ok := findBox(children, "iprp")
+	if !ok {
+		return missing(truncated)
+	}
+	iprpChildren, _ := readBoxes(iprp.data)
+	ipco, ok := findBox(iprpChildren, "ipco")
+	if !ok {
+		return missing(truncated)
+	}
+	properties, _ := readBoxes(ipco.data)
+
+	// property indexes are 1 based
+	indexes := make([]int, 0)
+	if ipma, ok := findBox(iprpChildren, "ipma"); ok && hasPrimary {
+		indexes = primaryProperties(ipma.data, primary)
+	}
+
+	var width, height float64
+	rotated := false
+	if len(indexes) == 0 {
+		// no associations, take biggest image
+		for _, p := range properties {
+			if w, h, ok := ispeSize(p); ok && w*h > width*height {
+				width, height = w, h
+			}
+		}
+	} else {
+		for _, i := range indexes {
+			if i < 1 || i > len(properties) {
+				continue
+			}
+			p := properties[i-1]
+			switch p.typ {
+			case "ispe":
+				width, height, _ = ispeSize(p)
+			case "clap":
+				if w, h, ok := clapSize(p); ok {
+					width, height = w, h
+				}
+			case "irot":
+				if len(p.data) >= 1 && p.data[0]&1 == 1 {
+					rotated = !rotated
+				}
+			}
+		}
+	}
+
+	if width == 0 || height == 0 {
+		return missing(truncated)
+	}
+	if rotated {
+		width, height = height, width
+	}
+	return checkSize(int64(math.Round(width)), int64(math.Round(height)))
+}
+
+// primaryProperties indexes of properties associated with item, in order of association
+func primaryProperties(ipma []byte, item uint32) []int {
+	if len(ipma) < 8 {
+		return nil
+	}
+	version, flags := ipma[0], ipma[3]
+	count := binary.BigEndian.Uint32(ipma[4:8])
+	data := ipma[8:]
+
+	for e := uint32(0); e < count; e++ {
+		var id uint32
+		if version < 1 {
+			if len(data) < 2 {
+				return nil
+			}
+			id, data = uint32(binary.BigEndian.Uint16(data)), data[2:]
+		} else {
+			if len(data) < 4 {
+				return nil
+			}
+			id, data = binary.BigEndian.Uint32(data), data[4:]
+		}
+		if len(data) < 1 {
+			return nil
+		}
+		associations := int(data[0])
+		data = data[1:]
+
+		indexes := make([]int, 0, associations)
+		for a := 0; a < associations; a++ {
+			if flags&1 == 1 {
+				if len(data) < 2 {
+					return nil
+				}
+				indexes = append(indexes, int(binary.BigEndian.Uint16(data)&0x7FFF))
+				data = data[2:]
+			} else {
+				if len(data) < 1 {
+					return nil
+				}
+				indexes = append(indexes, int(data[0]&0x7F))
+				data = data[1:]
+			}
+		}
+		if id == item {
+			return indexes
+		}
+	}
+	return nil
+}
+
+// ispeSize image spatial extents property
+func ispeSize(p box) (float64, float64, bool) {
+	if p.typ != "ispe" || len(p.data) < 12 {
+		return 0, 0, false
+	}
+	return float64(binary.BigEndian.Uint32(p.data[4:8])), float64(binary.BigEndian.Uint32(p.data[8:12])), true
+}
+
+// clapSize clean aperture width and height, stored as fractions
+func clapSize(p box) (float64, float64, bool) {
+	if len(p.data) < 16 {
+		return 0, 0, false
+	}
+	wN, wD := binary.BigEndian.Uint32(p.data[0:4]), binary.BigEndian.Uint32(p.data[4:8])
+	hN, hD := binary.BigEndian.Uint32(p.data[8:12]), binary.BigEndian.Uint32(p.data[12:16])
+	if wD == 0 || hD == 0 {
+		return 0, 0, false
+	}
+	return float64(wN) / float64(wD), float64(hN) / float64(hD), true
+}
@@ -0,0 +1,60 @@
+package imageutils
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"testing"
+)
+
+func TestHEIFDimensions(t *testing.T) {
+	var samples = []struct {
+		src  string
+		w, h int32
+	}{
+		{"samples/file.avif", 1200, 800},
+		{"samples/file.heic", 3024, 4032}, // rotated by irot, thumbnail item ignored
+	}
+
+	for _, sample := range samples {
+		data, err := ioutil.ReadFile(sample.src)
+		check(err)
+		if w, h, err := HEIFDimensions(data); err != nil || w != sample.w || h != sample.h {
+			t.Errorf("HEIFDimensions (%s) returned (%d, %d), expected %d, %d", sample.src, w, h, sample.w, sample.h)
+		}
+	}
+}
+
+func TestHEIFDimensionsClap(t *testing.T) {
+	// 1920x1080 coded size cropped to 1915.5x1077 by clean aperture
+	ispe := fullBox("ispe", u32(1920, 1080))
+	clap := testBox("clap", u32(3831, 2, 1077, 1, 0, 1, 0, 1))
+	ipma := fullBox("ipma", append(u32(1), 0, 1, 2, 0x81, 0x82))
+	meta := fullBox("meta", append(fullBox("pitm", []byte{0, 1}), testBox("iprp", append(testBox("ipco", append(ispe, clap...)), ipma...))...))
+	data := append(testBox("ftyp", []byte("avif\x00\x00\x00\x00mif1")), meta...)
+
+	if v := DetermineImageType(&data); v != "avif" {
+		t.Errorf("DetermineImageType returned %v, expected avif", v)
+	}
+	if w, h, err := HEIFDimensions(data); err != nil || w != 1916 || h != 1077 {
+		t.Errorf("HEIFDimensions returned (%d, %d), expected 1916, 1077", w, h)
+	}
+}
+
+func testBox(typ string, data []byte) []byte {
+	b := make([]byte, 8, 8+len(data))
+	binary.BigEndian.PutUint32(b, uint32(8+len(data)))
+	copy(b[4:], typ)
+	return append(b, data...)
+}
+
+func fullBox(typ string, data []byte) []byte {
+	return testBox(typ, append([]byte{0, 0, 0, 0}, data...))
+}
+
+func u32(values ...uint32) []byte {
+	b := make([]byte, 0, 4*len(values))
+	for _, v := range values {
+		b = binary.BigEndian.AppendUint32(b, v)
+	}
+	return b
+}
@@ -0,0 +1,67 @@
+package imageutils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"unicode/utf16"
+)
+
+// iccDescription profile description tag of ICC profile, "desc" text of version 2
+// or first "mluc" record of version 4, empty when profile is truncated
+func iccDescription(profile []byte) string {
+	if len(profile) < 132 || string(profile[36:40]) != "acsp" {
+		return ""
+	}
+
+	count := int64(binary.BigEndian.Uint32(profile[128:132]))
+	for i := int64(0); i < count; i++ {
+		entry := 132 + i*12
+		if entry+12 > int64(len(profile)) {
+			return ""
+		}
+		if string(profile[entry:entry+4]) != "desc" {
+			continue
+		}
+
+		offset := int64(binary.BigEndian.Uint32(profile[entry+4 : entry+8]))
+		size := int64(binary.BigEndian.Uint32(profile[entry+8 : entry+12]))
+		if size < 12 || offset+size > int64(len(profile)) {
+			return ""
+		}
+		return iccText(profile[offset : offset+size])
+	}
+	return ""
+}
+
+// iccText text of textDescriptionType or multiLocalizedUnicodeType tag
+func iccText(tag []byte) string {
+	switch string(tag[0:4]) {
+	case "desc":
+		// ASCII count including NUL, then text
+		n := int64(binary.BigEndian.Uint32(tag[8:12]))
+		text := tag[12:min(12+n, int64(len(tag)))]
+		if i := bytes.IndexByte(text, 0); i >= 0 {
+			text = text[:i]
+		}
+		return strings.TrimSpace(string(text))
+
+	case "mluc":
+		// records of language, country, length and offset of UTF-16BE text
+		if len(tag) < 28 || binary.BigEndian.Uint32(tag[8:12]) == 0 {
+			return ""
+		}
+		length := int64(binary.BigEndian.Uint32(tag[20:24]))
+		offset := int64(binary.BigEndian.Uint32(tag[24:28]))
+		if offset+length > int64(len(tag)) {
+			return ""
+		}
+		text := tag[offset : offset+length]
+		units := make([]uint16, len(text)/2)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(text[i*2:])
+		}
+		return strings.TrimSpace(strings.TrimRight(string(utf16.Decode(units)), "\x00"))
+	}
+	return ""
+}
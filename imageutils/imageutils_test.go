@@ -12,6 +12,15 @@ var testExt = []struct {
 	{"jpg", "samples/file.jpg"},
 	{"webp", "samples/file.webp"},
 	{"gif", "samples/file.gif"},
+	{"avif", "samples/file.avif"},
+	{"heic", "samples/file.heic"},
+	{"bmp", "samples/file.bmp"},
+	{"tiff", "samples/file.tiff"},
+	{"tiff", "samples/file2.tiff"},
+	{"ico", "samples/file.ico"},
+	{"cur", "samples/file.cur"},
+	{"jxl", "samples/file.jxl"},
+	{"jxl", "samples/file2.jxl"},
 }
 
 func TestDetermineImageType(t *testing.T) {
@@ -27,7 +36,7 @@ func TestDetermineImageType(t *testing.T) {
 func TestPNGDimensions(t *testing.T) {
 	data, err := ioutil.ReadFile("samples/file.png")
 	check(err)
-	if w, h := PNGDimensions(data); w != 521 || h != 450 {
+	if w, h, err := PNGDimensions(data); err != nil || w != 521 || h != 450 {
 		t.Errorf("PNGDimensions (samples/file.png) returned (%d, %d), expected %d, %d", w, h, 521, 450)
 	}
 }
@@ -35,7 +44,7 @@ func TestPNGDimensions(t *testing.T) {
 func TestGIFDimensions(t *testing.T) {
 	data, err := ioutil.ReadFile("samples/file.gif")
 	check(err)
-	if w, h := GIFDimensions(data); w != 251 || h != 201 {
+	if w, h, err := GIFDimensions(data); err != nil || w != 251 || h != 201 {
 		t.Errorf("PNGDimensions (samples/file.png) returned (%d, %d), expected %d, %d", w, h, 251, 201)
 	}
 }
@@ -49,12 +58,13 @@ func TestJPGHeaders(t *testing.T) {
 		{"samples/file.jpg", 251, 201},
 		{"samples/file2.jpg", 550, 449},
 		{"samples/file3.jpg", 800, 598},
+		{"samples/file4.jpg", 5616, 3744}, // EXIF thumbnail comes first
 	}
 
 	for _, sample := range samples {
 		data, err := ioutil.ReadFile(sample.src)
 		check(err)
-		if w, h := JPGHeaders(data); w != sample.w || h != sample.h {
+		if w, h, err := JPGHeaders(data); err != nil || w != sample.w || h != sample.h {
 			t.Errorf("JPGHeaders (%s) returned (%d, %d), expected %d, %d", sample.src, w, h, sample.w, sample.h)
 		}
 	}
@@ -73,7 +83,7 @@ func TestJPGHeadersQuick(t *testing.T) {
 	for _, sample := range samples {
 		data, err := ioutil.ReadFile(sample.src)
 		check(err)
-		if w, h := JPGHeadersQuick(data); w != sample.w || h != sample.h {
+		if w, h, err := JPGHeadersQuick(data); err != nil || w != sample.w || h != sample.h {
 			t.Errorf("JPGHeadersQuick (%s) returned (%d, %d), expected %d, %d", sample.src, w, h, sample.w, sample.h)
 		}
 	}
@@ -93,7 +103,7 @@ func TestJPGDimensions(t *testing.T) {
 	for _, sample := range samples {
 		data, err := ioutil.ReadFile(sample.src)
 		check(err)
-		if w, h := JPGDimensions(data); w != sample.w || h != sample.h {
+		if w, h, err := JPGDimensions(data); err != nil || w != sample.w || h != sample.h {
 			t.Errorf("JPGDimensions (%s) returned (%d, %d), expected %d, %d", sample.src, w, h, sample.w, sample.h)
 		}
 	}
@@ -113,7 +123,7 @@ func BenchmarkTestJPG(b *testing.B) {
 	data, err := ioutil.ReadFile("samples/file4.jpg")
 	check(err)
 	for i := 0; i < b.N; i++ {
-		_, _ = JPGHeaders(data)
+		_, _, _ = JPGHeaders(data)
 	}
 }
 
@@ -122,7 +132,7 @@ func BenchmarkTestJPGHeadersQuick(b *testing.B) {
 	data, err := ioutil.ReadFile("samples/file4.jpg")
 	check(err)
 	for i := 0; i < b.N; i++ {
-		_, _ = JPGHeadersQuick(data)
+		_, _, _ = JPGHeadersQuick(data)
 	}
 }
 func check(e error) {
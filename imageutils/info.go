@@ -0,0 +1,527 @@
+package imageutils
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Info image properties read from header and metadata blocks preceding image data
+type Info struct {
+	Header
+	MIME        string
+	BitDepth    int    // bits per channel, bits per index of palette images
+	ColorModel  string // one of Color* constants, empty when unknown
+	Interlaced  bool   // interlaced PNG or GIF, progressive JPEG
+	ICCProfile  string // description of embedded ICC profile
+	CameraMake  string // from EXIF
+	CameraModel string
+	DateTime    string // EXIF date of original, "2006:01:02 15:04:05" in camera time
+}
+
+// color models reported in Info
+const (
+	ColorGray    = "gray"
+	ColorRGB     = "rgb"
+	ColorPalette = "palette"
+	ColorYCbCr   = "ycbcr"
+	ColorCMYK    = "cmyk"
+	ColorYCCK    = "ycck"
+)
+
+// Probe detect image format and read its header and metadata. Errors are same as of
+// DecodeHeader, properties stored behind image data or beyond body are left empty
+func Probe(body []byte) (Info, error) {
+	header, err := DecodeHeader(body)
+	info := Info{Header: header}
+	if err != nil && !errors.Is(err, ErrNoIntrinsicSize) {
+		return info, err
+	}
+
+	f, _ := lookupFormat(body)
+	info.MIME = f.MIME
+	if f.Info != nil {
+		f.Info(body, &info)
+	}
+	return info, err
+}
+
+// exifInfo camera and date from EXIF block without "Exif\0\0" prefix
+func exifInfo(exif []byte, info *Info) {
+	t, offset, err := newTIFFReader(exif)
+	if err != nil {
+		return
+	}
+	entries, err := t.entries(offset)
+	if err != nil {
+		return
+	}
+	info.CameraMake, info.CameraModel, info.DateTime = t.camera(entries)
+}
+
+// pngInfo IHDR fields and ancillary chunks before image data
+func pngInfo(body []byte, info *Info) {
+	// size, bit depth, color type, compression, filter and interlace method
+	if len(body) < 29 {
+		return
+	}
+	info.BitDepth = int(body[24])
+	switch body[25] {
+	case 0, 4:
+		info.ColorModel = ColorGray
+	case 2, 6:
+		info.ColorModel = ColorRGB
+	case 3:
+		info.ColorModel = ColorPalette
+	}
+	info.Alpha = body[25]&4 != 0
+	info.Interlaced = body[28] == 1
+
+	for i := int64(8); i+8 <= int64(len(body)); {
+		size := int64(binary.BigEndian.Uint32(body[i : i+4]))
+		chunk := string(body[i+4 : i+8])
+		if i+8+size > int64(len(body)) {
+			return
+		}
+		data := body[i+8 : i+8+size]
+
+		switch chunk {
+		case "tRNS":
+			info.Alpha = true
+		case "iCCP":
+			info.ICCProfile = pngICCProfile(data)
+		case "eXIf":
+			exifInfo(data, info)
+		case "IDAT", "IEND":
+			return
+		}
+
+		i += 12 + size
+	}
+}
+
+// maxICCProfile limit of decompressed ICC profile
+const maxICCProfile = 1 << 20
+
+// pngICCProfile description of compressed profile, its name when profile can't be read
+func pngICCProfile(data []byte) string {
+	// name, NUL, compression method, zlib stream
+	end := bytes.IndexByte(data, 0)
+	if end < 0 || end+2 > len(data) {
+		return ""
+	}
+	name := string(data[:end])
+
+	z, err := zlib.NewReader(bytes.NewReader(data[end+2:]))
+	if err != nil {
+		return name
+	}
+	defer z.Close()
+	profile, _ := io.ReadAll(io.LimitReader(z, maxICCProfile))
+	if desc := iccDescription(profile); desc != "" {
+		return desc
+	}
+	return name
+}
+
+var (
+	iccHeader   = []byte("ICC_PROFILE\x00")
+	adobeHeader = []byte("Adobe")
+)
+
+// jpegInfo frame header, EXIF, ICC profile split into APP2 segments and Adobe color transform
+func jpegInfo(body []byte, info *Info) {
+	var icc []byte
+	transform := -1
+
+	_, _, err := jpegSegments(body, func(marker byte, segment []byte) {
+		switch {
+		case marker == 0xE1 && bytes.HasPrefix(segment, exifHeader):
+			exifInfo(segment[len(exifHeader):], info)
+		case marker == 0xE2 && bytes.HasPrefix(segment, iccHeader) && len(segment) >= len(iccHeader)+2:
+			// sequence number and count precede part of profile
+			icc = append(icc, segment[len(iccHeader)+2:]...)
+		case marker == 0xEE && bytes.HasPrefix(segment, adobeHeader) && len(segment) >= 12:
+			transform = int(segment[11])
+		}
+	})
+	if err != nil {
+		return
+	}
+	jpeg, err := JPGHeader(body)
+	if err != nil {
+		return
+	}
+
+	info.BitDepth = jpeg.Precision
+	info.Interlaced = jpeg.Progressive()
+	switch jpeg.Components {
+	case 1:
+		info.ColorModel = ColorGray
+	case 3:
+		info.ColorModel = ColorYCbCr
+		if transform == 0 {
+			info.ColorModel = ColorRGB
+		}
+	case 4:
+		info.ColorModel = ColorCMYK
+		if transform == 2 {
+			info.ColorModel = ColorYCCK
+		}
+	}
+	info.ICCProfile = iccDescription(icc)
+}
+
+// gifInfo color table size, transparency and interlacing of first frame
+func gifInfo(body []byte, info *Info) {
+	if len(body) < 13 {
+		return
+	}
+	info.ColorModel = ColorPalette
+	i := 13
+	if body[10]&0x80 != 0 {
+		info.BitDepth = int(body[10]&0x07) + 1
+		i += 3 << (body[10]&0x07 + 1)
+	}
+
+	for i < len(body) {
+		switch body[i] {
+		case 0x21:
+			// graphic control extension: block size, flags with transparency
+			if i+4 <= len(body) && body[i+1] == 0xF9 && body[i+2] >= 4 {
+				info.Alpha = info.Alpha || body[i+3]&1 != 0
+			}
+			next, err := skipSubBlocks(body, i+2)
+			if err != nil {
+				return
+			}
+			i = next
+
+		case 0x2C:
+			if i+10 > len(body) {
+				return
+			}
+			flags := body[i+9]
+			info.Interlaced = flags&0x40 != 0
+			if flags&0x80 != 0 {
+				info.BitDepth = int(flags&0x07) + 1
+			}
+			return
+
+		default:
+			return
+		}
+	}
+}
+
+// webpInfo color model of first image chunk, ICC profile and EXIF of extended format
+func webpInfo(body []byte, info *Info) {
+	info.BitDepth = 8
+
+	end := min(int64(binary.LittleEndian.Uint32(body[4:8]))+8, int64(len(body)))
+	for i := int64(12); i+8 <= end; {
+		chunk := string(body[i : i+4])
+		size := int64(binary.LittleEndian.Uint32(body[i+4 : i+8]))
+		data := body[i+8 : min(i+8+size, end)]
+		complete := i+8+size <= end
+
+		switch chunk {
+		case "VP8 ":
+			info.ColorModel = ColorYCbCr
+		case "VP8L":
+			info.ColorModel = ColorRGB
+		case "ANMF":
+			// frame header, then image chunks of first frame
+			if info.ColorModel == "" && len(data) >= 20 {
+				info.ColorModel = ColorYCbCr
+				if string(data[16:20]) == "VP8L" {
+					info.ColorModel = ColorRGB
+				}
+			}
+		case "ICCP":
+			if complete {
+				info.ICCProfile = iccDescription(data)
+			}
+		case "EXIF":
+			if complete {
+				exifInfo(bytes.TrimPrefix(data, exifHeader), info)
+			}
+		}
+
+		i += 8 + size + size&1
+	}
+}
+
+// heifInfo pixel information, color and alpha auxiliary image from item properties,
+// EXIF stored as item data is not read
+func heifInfo(body []byte, info *Info) {
+	top, _ := readBoxes(body)
+	meta, ok := findBox(top, "meta")
+	if !ok || len(meta.data) < 4 {
+		return
+	}
+	children, _ := readBoxes(meta.data[4:])
+	iprp, ok := findBox(children, "iprp")
+	if !ok {
+		return
+	}
+	iprpChildren, _ := readBoxes(iprp.data)
+	ipco, ok := findBox(iprpChildren, "ipco")
+	if !ok {
+		return
+	}
+	properties, _ := readBoxes(ipco.data)
+
+	for _, p := range properties {
+		switch p.typ {
+		case "pixi":
+			// version and flags, number of channels, bits of each channel
+			if info.BitDepth == 0 && len(p.data) >= 6 {
+				info.BitDepth = int(p.data[5])
+				info.ColorModel = ColorYCbCr
+				if p.data[4] == 1 {
+					info.ColorModel = ColorGray
+				}
+			}
+		case "colr":
+			if len(p.data) < 4 {
+				continue
+			}
+			switch string(p.data[:4]) {
+			case "prof", "rICC":
+				info.ICCProfile = iccDescription(p.data[4:])
+			case "nclx":
+				// primaries, transfer, matrix coefficients 0 means RGB
+				if len(p.data) >= 10 && binary.BigEndian.Uint16(p.data[8:10]) == 0 && info.ColorModel != ColorGray {
+					info.ColorModel = ColorRGB
+				}
+			}
+		case "auxC":
+			if len(p.data) > 4 && bytes.Contains(p.data[4:], []byte(":alpha")) {
+				info.Alpha = true
+			}
+		}
+	}
+}
+
+// bmpInfo bits per pixel, alpha mask and embedded profile of BITMAPV5HEADER
+func bmpInfo(body []byte, info *Info) {
+	if len(body) < 18 {
+		return
+	}
+	headerSize := binary.LittleEndian.Uint32(body[14:18])
+
+	var bpp int
+	if headerSize == 12 {
+		if len(body) < 26 {
+			return
+		}
+		bpp = int(binary.LittleEndian.Uint16(body[24:26]))
+	} else {
+		if len(body) < 30 {
+			return
+		}
+		bpp = int(binary.LittleEndian.Uint16(body[28:30]))
+	}
+
+	switch {
+	case bpp <= 8:
+		info.ColorModel, info.BitDepth = ColorPalette, bpp
+	case bpp == 16:
+		info.ColorModel, info.BitDepth = ColorRGB, 5
+	default:
+		info.ColorModel, info.BitDepth = ColorRGB, 8
+	}
+
+	// alpha mask of BITMAPV3INFOHEADER and later
+	if bpp == 32 && headerSize >= 56 && len(body) >= 70 && binary.LittleEndian.Uint32(body[66:70]) != 0 {
+		info.Alpha = true
+	}
+
+	// PROFILE_EMBEDDED color space, profile offset is relative to info header
+	if headerSize >= 124 && len(body) >= 138 && string(body[70:74]) == "DEBM" {
+		offset := 14 + int64(binary.LittleEndian.Uint32(body[126:130]))
+		size := int64(binary.LittleEndian.Uint32(body[130:134]))
+		if offset+size <= int64(len(body)) {
+			info.ICCProfile = iccDescription(body[offset : offset+size])
+		}
+	}
+}
+
+// tiffInfo sample format, color and EXIF fields of first IFD
+func tiffInfo(body []byte, info *Info) {
+	t, offset, err := newTIFFReader(body)
+	if err != nil {
+		return
+	}
+	entries, err := t.entries(offset)
+	if err != nil {
+		return
+	}
+
+	// baseline default is bilevel image
+	info.BitDepth = 1
+	if e, ok := findEntry(entries, tagBitsPerSample); ok {
+		if b, ok := t.bytes(e); ok && e.typ == 3 && len(b) >= 2 {
+			info.BitDepth = int(t.order.Uint16(b))
+		}
+	}
+	if e, ok := findEntry(entries, tagPhotometric); ok {
+		photometric, _ := t.uint(e)
+		switch photometric {
+		case 0, 1:
+			info.ColorModel = ColorGray
+		case 2:
+			info.ColorModel = ColorRGB
+		case 3:
+			info.ColorModel = ColorPalette
+		case 5:
+			info.ColorModel = ColorCMYK
+		case 6:
+			info.ColorModel = ColorYCbCr
+		}
+	}
+	// associated or unassociated alpha
+	if e, ok := findEntry(entries, tagExtraSamples); ok {
+		if extra, ok := t.uint(e); ok && (extra == 1 || extra == 2) {
+			info.Alpha = true
+		}
+	}
+	if e, ok := findEntry(entries, tagICCProfile); ok {
+		if profile, ok := t.bytes(e); ok {
+			info.ICCProfile = iccDescription(profile)
+		}
+	}
+	info.CameraMake, info.CameraModel, info.DateTime = t.camera(entries)
+}
+
+// icoInfo properties of largest image, PNG entries are read as PNG
+func icoInfo(body []byte, info *Info) {
+	_, _, entry, err := largestICOEntry(body)
+	if err != nil || entry == nil {
+		return
+	}
+
+	offset := int64(binary.LittleEndian.Uint32(entry[12:16]))
+	if offset < int64(len(body)) && bytes.HasPrefix(body[offset:], pngSignature) {
+		pngInfo(body[offset:], info)
+		return
+	}
+
+	// bit count of directory entry, or of bitmap header when entry leaves it empty
+	bpp := int(binary.LittleEndian.Uint16(entry[6:8]))
+	if bpp == 0 && offset+16 <= int64(len(body)) {
+		bpp = int(binary.LittleEndian.Uint16(body[offset+14 : offset+16]))
+	}
+	switch {
+	case bpp == 0:
+	case bpp <= 8:
+		info.ColorModel, info.BitDepth = ColorPalette, bpp
+	default:
+		info.ColorModel, info.BitDepth = ColorRGB, 8
+		info.Alpha = bpp == 32
+	}
+}
+
+// jxlInfo bit depth, alpha channel and color space from image metadata, EXIF box of container.
+// Embedded ICC profile is entropy coded and not read
+func jxlInfo(body []byte, info *Info) {
+	if bytes.HasPrefix(body, jxlContainerHeader) {
+		boxes, _ := readBoxes(body)
+		// offset of TIFF header, then EXIF
+		if exif, ok := findBox(boxes, "Exif"); ok && len(exif.data) >= 4 {
+			offset := int64(binary.BigEndian.Uint32(exif.data[0:4]))
+			if 4+offset <= int64(len(exif.data)) {
+				exifInfo(exif.data[4+offset:], info)
+			}
+		}
+	}
+
+	codestream, err := jxlCodestream(body)
+	if err != nil {
+		return
+	}
+	r := bitReader{data: codestream[2:]}
+	jxlSize(&r)
+
+	depth, model, alpha := jxlMetadata(&r)
+	if !r.short {
+		info.BitDepth, info.ColorModel, info.Alpha = depth, model, alpha
+	}
+}
+
+// enum distribution of JPEG XL headers
+var jxlEnum = [4][2]uint32{{0, 0}, {1, 0}, {2, 4}, {18, 6}}
+
+// jxlMetadata fields of ImageMetadata up to color encoding, color model is empty when
+// extra channel with custom fields is in the way
+func jxlMetadata(r *bitReader) (depth int, model string, alpha bool) {
+	// all_default
+	if r.read(1) == 1 {
+		return 8, ColorRGB, false
+	}
+
+	// extra_fields: orientation, intrinsic size, preview and animation headers
+	if r.read(1) == 1 {
+		r.read(3)
+		if r.read(1) == 1 {
+			jxlSize(r)
+		}
+		if r.read(1) == 1 {
+			div8 := r.read(1) == 1
+			previewSize := func() {
+				if div8 {
+					r.u32Dist([4][2]uint32{{16, 0}, {32, 0}, {1, 5}, {33, 9}})
+				} else {
+					r.u32Dist([4][2]uint32{{1, 6}, {65, 8}, {321, 10}, {1345, 12}})
+				}
+			}
+			previewSize()
+			if r.read(3) == 0 {
+				previewSize()
+			}
+		}
+		if r.read(1) == 1 {
+			r.u32Dist([4][2]uint32{{100, 0}, {1000, 0}, {1, 10}, {1, 30}})
+			r.u32Dist([4][2]uint32{{1, 0}, {1001, 0}, {1, 8}, {1, 10}})
+			r.u32Dist([4][2]uint32{{0, 0}, {0, 3}, {0, 16}, {0, 32}})
+			r.read(1)
+		}
+	}
+
+	// integer or floating point samples
+	if r.read(1) == 0 {
+		depth = int(r.u32Dist([4][2]uint32{{8, 0}, {10, 0}, {12, 0}, {1, 6}}))
+	} else {
+		depth = int(r.u32Dist([4][2]uint32{{32, 0}, {16, 0}, {24, 0}, {1, 6}}))
+		r.read(4)
+	}
+
+	// modular_16_bit_buffers, extra channels which are alpha by default
+	r.read(1)
+	extra := r.u32Dist([4][2]uint32{{0, 0}, {1, 0}, {2, 4}, {1, 12}})
+	for i := uint32(0); i < extra && !r.short; i++ {
+		if r.read(1) == 1 {
+			alpha = true
+			continue
+		}
+		// custom channel, its type is known but remaining fields are not parsed
+		if r.u32Dist(jxlEnum) == 0 {
+			alpha = true
+		}
+		return depth, "", alpha
+	}
+
+	// xyb_encoded, then color encoding with color space when not default
+	r.read(1)
+	model = ColorRGB
+	if r.read(1) == 0 {
+		r.read(1)
+		if r.u32Dist(jxlEnum) == 1 {
+			model = ColorGray
+		}
+	}
+	return depth, model, alpha
+}
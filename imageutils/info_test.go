@@ -0,0 +1,159 @@
+package imageutils
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io/ioutil"
+	"testing"
+	"unicode/utf16"
+)
+
+func TestProbe(t *testing.T) {
+	var samples = []struct {
+		src   string
+		mime  string
+		depth int
+		model string
+		alpha bool
+		inter bool
+	}{
+		{"samples/file.png", "image/png", 8, ColorRGB, true, false},
+		{"samples/file2.png", "image/png", 8, ColorPalette, false, false},
+		{"samples/file.jpg", "image/jpeg", 8, ColorYCbCr, false, true},
+		{"samples/file2.jpg", "image/jpeg", 8, ColorYCbCr, false, false},
+		{"samples/file.gif", "image/gif", 8, ColorPalette, false, false},
+		{"samples/file.webp", "image/webp", 8, ColorYCbCr, true, false},
+		{"samples/file.bmp", "image/bmp", 8, ColorRGB, false, false},
+		{"samples/file2.tiff", "image/tiff", 1, ColorGray, false, false},
+		{"samples/file.cur", "image/x-icon", 4, ColorPalette, false, false},
+		{"samples/file2.jxl", "image/jxl", 8, ColorRGB, false, false},
+		{"samples/file.svg", "image/svg+xml", 0, "", false, false},
+	}
+
+	for _, sample := range samples {
+		data, err := ioutil.ReadFile(sample.src)
+		check(err)
+		info, err := Probe(data)
+		if err != nil || info.MIME != sample.mime || info.BitDepth != sample.depth || info.ColorModel != sample.model ||
+			info.Alpha != sample.alpha || info.Interlaced != sample.inter {
+			t.Errorf("Probe (%s) returned %+v, %v", sample.src, info, err)
+		}
+	}
+
+	data, err := ioutil.ReadFile("samples/file4.jpg")
+	check(err)
+	info, err := Probe(data)
+	if err != nil || info.Width != 5616 || info.CameraMake != "Canon" || info.CameraModel != "Canon EOS-1Ds Mark III" ||
+		info.DateTime != "2012:03:14 13:30:40" {
+		t.Errorf("Probe (samples/file4.jpg) returned %+v, %v", info, err)
+	}
+
+	if _, err := Probe([]byte("GIF89a")); !errors.Is(err, ErrTruncated) {
+		t.Errorf("Probe of truncated GIF returned %v, expected %v", err, ErrTruncated)
+	}
+}
+
+// iccProfile minimal profile with single description tag
+func iccProfile(tag []byte) []byte {
+	profile := make([]byte, 144)
+	copy(profile[36:], "acsp")
+	binary.BigEndian.PutUint32(profile[128:], 1)
+	copy(profile[132:], "desc")
+	binary.BigEndian.PutUint32(profile[136:], 144)
+	binary.BigEndian.PutUint32(profile[140:], uint32(len(tag)))
+	profile = append(profile, tag...)
+	binary.BigEndian.PutUint32(profile[0:], uint32(len(profile)))
+	return profile
+}
+
+// descTag version 2 textDescriptionType
+func descTag(text string) []byte {
+	tag := append([]byte("desc\x00\x00\x00\x00"), binary.BigEndian.AppendUint32(nil, uint32(len(text)+1))...)
+	return append(append(tag, text...), 0)
+}
+
+// mlucTag version 4 multiLocalizedUnicodeType with single record
+func mlucTag(text string) []byte {
+	units := utf16.Encode([]rune(text))
+	tag := []byte("mluc\x00\x00\x00\x00")
+	tag = binary.BigEndian.AppendUint32(tag, 1)
+	tag = binary.BigEndian.AppendUint32(tag, 12)
+	tag = append(tag, "enUS"...)
+	tag = binary.BigEndian.AppendUint32(tag, uint32(len(units)*2))
+	tag = binary.BigEndian.AppendUint32(tag, 28)
+	for _, u := range units {
+		tag = binary.BigEndian.AppendUint16(tag, u)
+	}
+	return tag
+}
+
+func TestICCDescription(t *testing.T) {
+	if desc := iccDescription(iccProfile(descTag("sRGB IEC61966-2.1"))); desc != "sRGB IEC61966-2.1" {
+		t.Errorf("iccDescription (desc) returned %q", desc)
+	}
+	if desc := iccDescription(iccProfile(mlucTag("Display P3"))); desc != "Display P3" {
+		t.Errorf("iccDescription (mluc) returned %q", desc)
+	}
+	profile := iccProfile(descTag("Adobe RGB (1998)"))
+	for n := range profile {
+		if desc := iccDescription(profile[:n]); desc != "" {
+			t.Fatalf("iccDescription of %d bytes returned %q", n, desc)
+		}
+	}
+}
+
+func TestProbeICCProfile(t *testing.T) {
+	profile := iccProfile(mlucTag("Display P3"))
+
+	// profile split into two APP2 segments right after SOI
+	data, err := ioutil.ReadFile("samples/file2.jpg")
+	check(err)
+	jpeg := append([]byte{}, data[:2]...)
+	for i, part := range [][]byte{profile[:100], profile[100:]} {
+		segment := append(append([]byte{}, iccHeader...), byte(i+1), 2)
+		segment = append(segment, part...)
+		jpeg = append(jpeg, 0xFF, 0xE2)
+		jpeg = binary.BigEndian.AppendUint16(jpeg, uint16(len(segment)+2))
+		jpeg = append(jpeg, segment...)
+	}
+	jpeg = append(jpeg, data[2:]...)
+	if info, err := Probe(jpeg); err != nil || info.ICCProfile != "Display P3" || info.Width != 550 {
+		t.Errorf("Probe (jpeg with ICC profile) returned %+v, %v", info, err)
+	}
+
+	// compressed iCCP chunk after IHDR
+	data, err = ioutil.ReadFile("samples/file.png")
+	check(err)
+	var compressed bytes.Buffer
+	z := zlib.NewWriter(&compressed)
+	z.Write(profile)
+	z.Close()
+	chunk := append([]byte("iCCP"), append([]byte("icc\x00\x00"), compressed.Bytes()...)...)
+	png := append([]byte{}, data[:33]...)
+	png = binary.BigEndian.AppendUint32(png, uint32(len(chunk)-4))
+	png = append(png, chunk...)
+	png = binary.BigEndian.AppendUint32(png, crc32.ChecksumIEEE(chunk))
+	png = append(png, data[33:]...)
+	if info, err := Probe(png); err != nil || info.ICCProfile != "Display P3" || info.Width != 521 {
+		t.Errorf("Probe (png with iCCP) returned %+v, %v", info, err)
+	}
+}
+
+func FuzzProbe(f *testing.F) {
+	for _, data := range readSamples(f, "samples/*") {
+		f.Add(data)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		info, err := Probe(data)
+		if err != nil && !errors.Is(err, ErrFormat) && !errors.Is(err, ErrTruncated) &&
+			!errors.Is(err, ErrCorrupt) && !errors.Is(err, ErrNoIntrinsicSize) {
+			t.Errorf("Probe returned unexpected error %v", err)
+		}
+		if info.BitDepth < 0 {
+			t.Errorf("Probe returned %+v", info)
+		}
+	})
+}
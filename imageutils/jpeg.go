@@ -0,0 +1,121 @@
+package imageutils
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// JPEGInfo frame header of JPEG image
+type JPEGInfo struct {
+	Width       int32 // as stored, before orientation is applied
+	Height      int32
+	Orientation int  // EXIF orientation 1-8, 1 when missing
+	Marker      byte // SOF marker, 0xC0 baseline, 0xC2 progressive...
+	Precision   int  // bits per sample
+	Components  int  // 1 grayscale, 3 YCbCr or RGB, 4 CMYK or YCCK
+}
+
+// Rotated orientation swaps width and height of displayed image
+func (i JPEGInfo) Rotated() bool {
+	return i.Orientation >= 5 && i.Orientation <= 8
+}
+
+// Progressive frame is coded in several scans
+func (i JPEGInfo) Progressive() bool {
+	return i.Marker == 0xC2 || i.Marker == 0xC6 || i.Marker == 0xCA || i.Marker == 0xCE
+}
+
+var exifHeader = []byte("Exif\x00\x00")
+
+// isSOF start of frame markers, C4 (DHT), C8 (JPG) and CC (DAC) share the range
+func isSOF(marker byte) bool {
+	return marker >= 0xC0 && marker <= 0xCF && marker != 0xC4 && marker != 0xC8 && marker != 0xCC
+}
+
+// jpegSegments walk marker segments of JPEG up to first frame header, visit is called for
+// segments before it. Segments are skipped by their length, so frames of EXIF thumbnails
+// and bytes of entropy coded data never match. Frame header may be truncated
+func jpegSegments(body []byte, visit func(marker byte, segment []byte)) (byte, []byte, error) {
+	if len(body) < 2 {
+		return 0, nil, ErrTruncated
+	}
+	if body[0] != 0xFF || body[1] != 0xD8 {
+		return 0, nil, ErrFormat
+	}
+
+	i := 2
+	for {
+		if i >= len(body) {
+			return 0, nil, ErrTruncated
+		}
+		if body[i] != 0xFF {
+			return 0, nil, ErrCorrupt
+		}
+		// any number of fill bytes may precede marker
+		for i < len(body) && body[i] == 0xFF {
+			i++
+		}
+		if i >= len(body) {
+			return 0, nil, ErrTruncated
+		}
+		marker := body[i]
+		i++
+
+		switch {
+		case marker == 0x01 || (marker >= 0xD0 && marker <= 0xD8):
+			// standalone markers without length
+			continue
+		case marker == 0xD9 || marker == 0xDA:
+			// end of image or scan before any frame header
+			return 0, nil, ErrCorrupt
+		}
+
+		if i+2 > len(body) {
+			return 0, nil, ErrTruncated
+		}
+		length := int(binary.BigEndian.Uint16(body[i : i+2]))
+		if length < 2 {
+			return 0, nil, ErrCorrupt
+		}
+		end := i + length
+		segment := body[i+2 : min(end, len(body))]
+
+		if isSOF(marker) {
+			return marker, segment, nil
+		}
+		visit(marker, segment)
+
+		i = end
+	}
+}
+
+// JPGHeader read frame header of JPEG and orientation from EXIF block preceding it
+func JPGHeader(body []byte) (JPEGInfo, error) {
+	info := JPEGInfo{Orientation: 1}
+
+	marker, frame, err := jpegSegments(body, func(marker byte, segment []byte) {
+		if marker == 0xE1 && bytes.HasPrefix(segment, exifHeader) {
+			info.Orientation = exifOrientation(segment[len(exifHeader):])
+		}
+	})
+	if err != nil {
+		return JPEGInfo{Orientation: 1}, err
+	}
+
+	// precision, height, width, number of components
+	if len(frame) < 5 {
+		return JPEGInfo{Orientation: 1}, ErrTruncated
+	}
+	info.Marker = marker
+	info.Precision = int(frame[0])
+	info.Height = int32(binary.BigEndian.Uint16(frame[1:3]))
+	info.Width = int32(binary.BigEndian.Uint16(frame[3:5]))
+	if len(frame) >= 6 {
+		info.Components = int(frame[5])
+	}
+	if info.Width == 0 || info.Height == 0 {
+		// height defined later by DNL marker is not supported
+		return JPEGInfo{Orientation: 1}, ErrCorrupt
+	}
+	return info, nil
+}
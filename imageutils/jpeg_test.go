@@ -0,0 +1,56 @@
+package imageutils
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func TestJPGHeader(t *testing.T) {
+	var samples = []struct {
+		src         string
+		w, h        int32
+		orientation int
+		marker      byte
+	}{
+		{"samples/file.jpg", 251, 201, 1, 0xC2},
+		{"samples/file2.jpg", 550, 449, 1, 0xC0},
+		{"samples/file3.jpg", 800, 598, 1, 0xC2},
+		{"samples/file4.jpg", 5616, 3744, 1, 0xC0}, // EXIF thumbnail 160x107 comes first
+		{"samples/file5.jpg", 800, 598, 6, 0xC2},   // EXIF first, with 251x201 thumbnail
+	}
+
+	for _, sample := range samples {
+		data, err := ioutil.ReadFile(sample.src)
+		check(err)
+		info, err := JPGHeader(data)
+		if err != nil || info.Width != sample.w || info.Height != sample.h || info.Orientation != sample.orientation || info.Marker != sample.marker {
+			t.Errorf("JPGHeader (%s) returned %+v, %v, expected %dx%d orientation %d marker %X",
+				sample.src, info, err, sample.w, sample.h, sample.orientation, sample.marker)
+		}
+	}
+
+	data, err := ioutil.ReadFile("samples/file5.jpg")
+	check(err)
+	if w, h, err := JPGDimensions(data); err != nil || w != 598 || h != 800 {
+		t.Errorf("JPGDimensions (samples/file5.jpg) returned (%d, %d, %v), expected 598, 800", w, h, err)
+	}
+}
+
+func TestJPGHeaderMarkers(t *testing.T) {
+	// DHT with bytes looking like SOF, fill bytes, restart marker, then given frame
+	prefix := []byte{0xFF, 0xD8, 0xFF, 0xC4, 0x00, 0x08, 0xFF, 0xC0, 0x00, 0x11, 0x08, 0x00, 0xFF, 0xFF, 0xD0}
+	frame := []byte{0x00, 0x0B, 0x08, 0x01, 0xE0, 0x02, 0x80, 0x01, 0x01, 0x11, 0x00}
+
+	for _, marker := range []byte{0xC0, 0xC1, 0xC2, 0xC3, 0xC5, 0xC6, 0xC7, 0xC9, 0xCA, 0xCB, 0xCD, 0xCE, 0xCF} {
+		data := append(append(append([]byte{}, prefix...), 0xFF, marker), frame...)
+		info, err := JPGHeader(data)
+		if err != nil || info.Width != 640 || info.Height != 480 || info.Marker != marker {
+			t.Errorf("JPGHeader (SOF %X) returned %+v, %v, expected 640x480", marker, info, err)
+		}
+	}
+
+	// scan before frame header
+	if _, err := JPGHeader([]byte{0xFF, 0xD8, 0xFF, 0xDA, 0x00, 0x02}); err != ErrCorrupt {
+		t.Errorf("JPGHeader without frame returned %v, expected %v", err, ErrCorrupt)
+	}
+}
@@ -0,0 +1,196 @@
+package imageutils
+
+import (
+	"bytes"
+	"sync"
+	"sync/atomic"
+)
+
+// Format header-only decoder of image format, similar to image.RegisterFormat but reading
+// just enough bytes to know dimensions
+type Format struct {
+	Name      string                               // type reported in Header, like "png"
+	MIME      string                               // reported by Probe
+	Match     func(head []byte) bool               // magic bytes, head may be shorter than signature
+	Decode    func(body []byte) (Header, error)    // ErrTruncated when body ends before dimensions
+	Animation func(body []byte) (Animation, error) // optional, frames of animated images
+	Info      func(body []byte, info *Info)        // optional, metadata added to decoded header
+}
+
+var (
+	formatsMu     sync.Mutex
+	atomicFormats atomic.Pointer[[]Format]
+)
+
+// RegisterFormat add format to registry. Formats are matched in order of registration,
+// built-in formats come first, so they can't be replaced
+func RegisterFormat(f Format) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+
+	formats := Formats()
+	formats = append(formats, f)
+	atomicFormats.Store(&formats)
+}
+
+// Formats registered formats in order of matching
+func Formats() []Format {
+	formats := atomicFormats.Load()
+	if formats == nil {
+		return nil
+	}
+	return append([]Format(nil), *formats...)
+}
+
+// lookupFormat first registered format matching body
+func lookupFormat(body []byte) (Format, bool) {
+	formats := atomicFormats.Load()
+	if formats == nil {
+		return Format{}, false
+	}
+	for _, f := range *formats {
+		if f.Match(body) {
+			return f, true
+		}
+	}
+	return Format{}, false
+}
+
+// prefix matcher of fixed signatures
+func prefix(signatures ...string) func([]byte) bool {
+	return func(head []byte) bool {
+		for _, s := range signatures {
+			if bytes.HasPrefix(head, []byte(s)) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// dimensions decoder of formats without other header properties
+func dimensions(parse func([]byte) (int32, int32, error)) func([]byte) (Header, error) {
+	return func(body []byte) (Header, error) {
+		w, h, err := parse(body)
+		return Header{Width: w, Height: h}, err
+	}
+}
+
+// matchWEBP RIFF container with WEBP form type, head shorter than 12 bytes matches
+// when it's start of such signature
+func matchWEBP(head []byte) bool {
+	if len(head) < 2 {
+		return false
+	}
+	if n := min(len(head), 4); string(head[:n]) != "RIFF"[:n] {
+		return false
+	}
+	// bytes 4-8 are size of RIFF chunk
+	if len(head) <= 8 {
+		return true
+	}
+	n := min(len(head), 12) - 8
+	return string(head[8:8+n]) == "WEBP"[:n]
+}
+
+// matchICO ICO or CUR with at least one entry with reserved byte 0
+func matchICO(kind byte) func([]byte) bool {
+	return func(head []byte) bool {
+		return len(head) >= 6 && head[0] == 0 && head[1] == 0 && head[2] == kind && head[3] == 0 &&
+			(head[4] != 0 || head[5] != 0) && (len(head) < 10 || head[9] == 0)
+	}
+}
+
+func init() {
+	RegisterFormat(Format{
+		Name:      "png",
+		MIME:      "image/png",
+		Match:     prefix("\x89PNG"),
+		Decode:    dimensions(PNGDimensions),
+		Animation: PNGAnimation,
+		Info:      pngInfo,
+	})
+	RegisterFormat(Format{
+		Name:   "jpg",
+		MIME:   "image/jpeg",
+		Match:  prefix("\xFF\xD8"),
+		Decode: dimensions(JPGDimensions),
+		Info:   jpegInfo,
+	})
+	RegisterFormat(Format{
+		Name:      "gif",
+		MIME:      "image/gif",
+		Match:     prefix("GIF8"),
+		Decode:    dimensions(GIFDimensions),
+		Animation: GIFAnimation,
+		Info:      gifInfo,
+	})
+	// root element might follow XML declaration, comments and DOCTYPE
+	RegisterFormat(Format{
+		Name: "svg",
+		MIME: "image/svg+xml",
+		Match: func(head []byte) bool {
+			return isSVG(bytes.TrimRight(head, "\x00"))
+		},
+		Decode: func(body []byte) (Header, error) {
+			w, h, err := SVGDimensions(body)
+			return Header{Width: w, Height: h, Vector: true}, err
+		},
+	})
+	// ISO-BMFF, brands tell AVIF from HEIC
+	for _, name := range []string{"avif", "heic"} {
+		RegisterFormat(Format{
+			Name:   name,
+			MIME:   "image/" + name,
+			Match:  func(head []byte) bool { return heifBrand(head) == name },
+			Decode: dimensions(HEIFDimensions),
+			Info:   heifInfo,
+		})
+	}
+	RegisterFormat(Format{
+		Name:   "tiff",
+		MIME:   "image/tiff",
+		Match:  prefix("II*\x00", "MM\x00*"),
+		Decode: dimensions(TIFFDimensions),
+		Info:   tiffInfo,
+	})
+	RegisterFormat(Format{
+		Name:   "ico",
+		MIME:   "image/x-icon",
+		Match:  matchICO(1),
+		Decode: dimensions(ICODimensions),
+		Info:   icoInfo,
+	})
+	RegisterFormat(Format{
+		Name:   "cur",
+		MIME:   "image/x-icon",
+		Match:  matchICO(2),
+		Decode: dimensions(ICODimensions),
+		Info:   icoInfo,
+	})
+	RegisterFormat(Format{
+		Name:   "jxl",
+		MIME:   "image/jxl",
+		Match:  prefix(string(jxlSignature), string(jxlContainerHeader)),
+		Decode: dimensions(JXLDimensions),
+		Info:   jxlInfo,
+	})
+	RegisterFormat(Format{
+		Name:   "bmp",
+		MIME:   "image/bmp",
+		Match:  prefix("BM"),
+		Decode: dimensions(BMPDimensions),
+		Info:   bmpInfo,
+	})
+	RegisterFormat(Format{
+		Name:  "webp",
+		MIME:  "image/webp",
+		Match: matchWEBP,
+		Decode: func(body []byte) (Header, error) {
+			info, err := WEBPHeader(body)
+			return Header{Width: info.Width, Height: info.Height, Animated: info.Animated, Alpha: info.Alpha}, err
+		},
+		Animation: WEBPAnimation,
+		Info:      webpInfo,
+	})
+}
@@ -0,0 +1,96 @@
+package imageutils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// qoiHeader QOI image header, magic, width, height, channels and colorspace
+func qoiHeader(w, h uint32) []byte {
+	b := append([]byte("qoif"), binary.BigEndian.AppendUint32(nil, w)...)
+	return append(binary.BigEndian.AppendUint32(b, h), 4, 0)
+}
+
+func TestRegisterFormat(t *testing.T) {
+	if formats := Formats(); len(formats) == 0 || formats[0].Name != "png" {
+		t.Fatalf("Formats returned %v, expected built-in formats starting with png", formats)
+	}
+
+	// registry is global, keep other tests on built-in formats
+	saved := atomicFormats.Load()
+	t.Cleanup(func() {
+		formatsMu.Lock()
+		defer formatsMu.Unlock()
+		atomicFormats.Store(saved)
+	})
+
+	RegisterFormat(Format{
+		Name:  "qoi",
+		MIME:  "image/qoi",
+		Match: prefix("qoif"),
+		Decode: func(body []byte) (Header, error) {
+			if len(body) < 14 {
+				return Header{}, ErrTruncated
+			}
+			return Header{
+				Width:  int32(binary.BigEndian.Uint32(body[4:8])),
+				Height: int32(binary.BigEndian.Uint32(body[8:12])),
+				Alpha:  body[12] == 4,
+			}, nil
+		},
+		Info: func(body []byte, info *Info) {
+			info.BitDepth, info.ColorModel = 8, ColorRGB
+		},
+	})
+
+	data := append(qoiHeader(640, 480), make([]byte, 100)...)
+	if v := DetermineImageType(&data); v != "qoi" {
+		t.Errorf("DetermineImageType (qoi) returned %v", v)
+	}
+
+	want := Header{Type: "qoi", Width: 640, Height: 480, Alpha: true}
+	if header, err := DecodeHeader(data); err != nil || header != want {
+		t.Errorf("DecodeHeader (qoi) returned %+v, %v, expected %+v", header, err, want)
+	}
+	if info, err := Probe(data); err != nil || info.MIME != "image/qoi" || info.BitDepth != 8 || info.Width != 640 {
+		t.Errorf("Probe (qoi) returned %+v, %v", info, err)
+	}
+	if _, err := DecodeAnimation(data); !errors.Is(err, ErrFormat) {
+		t.Errorf("DecodeAnimation (qoi) returned %v, expected %v", err, ErrFormat)
+	}
+
+	// header split between reads
+	var h HeaderReader
+	r := bytes.NewReader(data[:10])
+	if _, err := h.Decode(r); !errors.Is(err, ErrTruncated) {
+		t.Errorf("Decode of partial qoi header returned %v, expected %v", err, ErrTruncated)
+	}
+	if header, err := h.Decode(bytes.NewReader(data[10:])); err != nil || header != want {
+		t.Errorf("Decode (qoi) returned %+v, %v, expected %+v", header, err, want)
+	}
+}
+
+func TestMatchWEBP(t *testing.T) {
+	tests := map[string]bool{
+		"R":                            false,
+		"RI":                           true,
+		"RIFF\x10\x00\x00":             true,
+		"RIFF\x10\x00\x00\x00WE":       true,
+		"RIFF\x10\x00\x00\x00WEBPVP8 ": true,
+		"RIFF\x10\x00\x00\x00WAVEfmt ": false,
+		"RIFF\x10\x00\x00\x00AVI LIST": false,
+		"RIFX\x10\x00\x00\x00WEBP":     false,
+	}
+	for head, expected := range tests {
+		if result := matchWEBP([]byte(head)); result != expected {
+			t.Errorf("matchWEBP(%q) = %v, want %v", head, result, expected)
+		}
+	}
+
+	wav := append([]byte("RIFF\x24\x08\x00\x00WAVEfmt "), make([]byte, 600)...)
+	if v := DetermineImageType(&wav); v != "" {
+		t.Errorf("DetermineImageType (wav) returned %v", v)
+	}
+}
@@ -0,0 +1,179 @@
+package imageutils
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrNoIntrinsicSize image scales to any size, like SVG with percentage sizes and no viewBox
+var ErrNoIntrinsicSize = errors.New("imageutils: image has no intrinsic size")
+
+// svgUnits CSS pixels per unit, font relative units use default 16px font
+var svgUnits = map[string]float64{
+	"":    1,
+	"px":  1,
+	"pt":  96.0 / 72,
+	"pc":  16,
+	"in":  96,
+	"cm":  96 / 2.54,
+	"mm":  96 / 25.4,
+	"q":   96 / 101.6,
+	"em":  16,
+	"rem": 16,
+	"ex":  8,
+}
+
+var svgLength = regexp.MustCompile(`^([+-]?(?:\d+\.?\d*|\.\d+)(?:[eE][+-]?\d+)?)\s*([a-zA-Z%]*)$`)
+
+// xmlProlog skip BOM, XML declaration, comments and DOCTYPE before root element,
+// complete is false when data ends inside one of them
+func xmlProlog(data []byte) (rest []byte, complete bool) {
+	rest = bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF"))
+	for {
+		rest = bytes.TrimLeft(rest, " \t\r\n")
+
+		var end []byte
+		switch {
+		case bytes.HasPrefix(rest, []byte("<?")):
+			end = []byte("?>")
+		case bytes.HasPrefix(rest, []byte("<!--")):
+			end = []byte("-->")
+		case bytes.HasPrefix(rest, []byte("<!")):
+			// DOCTYPE, possibly with internal subset
+			end = []byte(">")
+			if i := bytes.IndexAny(rest, "[>"); i < 0 {
+				return rest, false
+			} else if rest[i] == '[' {
+				end = []byte("]>")
+			}
+		default:
+			return rest, true
+		}
+
+		i := bytes.Index(rest, end)
+		if i < 0 {
+			return rest, false
+		}
+		rest = rest[i+len(end):]
+	}
+}
+
+// isSVG skip XML declaration, comments and DOCTYPE and check whether root element is svg.
+// When data ends before root element any <svg tag is good enough
+func isSVG(data []byte) bool {
+	rest, complete := xmlProlog(data)
+	if !complete {
+		return bytes.Contains(data, []byte("<svg"))
+	}
+	return bytes.HasPrefix(rest, []byte("<svg")) && len(rest) > 4 &&
+		strings.ContainsRune(" \t\r\n>/", rune(rest[4]))
+}
+
+// partialSVG data ends inside XML prolog or before name of root element is complete,
+// more data might turn out to be SVG
+func partialSVG(data []byte) bool {
+	rest, complete := xmlProlog(data)
+	return !complete || (len(rest) <= 4 && bytes.HasPrefix([]byte("<svg"), rest))
+}
+
+// SVGDimensions reads root element of SVG and returns its size in CSS pixels. Missing or
+// percentage width and height come from viewBox, ErrNoIntrinsicSize when there is none
+func SVGDimensions(body []byte) (int32, int32, error) {
+	d := xml.NewDecoder(bytes.NewReader(body))
+	d.Strict = false
+
+	for {
+		token, err := d.Token()
+		if err != nil {
+			var syntax *xml.SyntaxError
+			if err == io.EOF || (errors.As(err, &syntax) && syntax.Msg == "unexpected EOF") {
+				return 0, 0, ErrTruncated
+			}
+			return 0, 0, ErrCorrupt
+		}
+
+		root, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if root.Name.Local != "svg" {
+			return 0, 0, ErrFormat
+		}
+		return svgSize(root.Attr)
+	}
+}
+
+// svgSize size of root element from width, height and viewBox attributes
+func svgSize(attrs []xml.Attr) (int32, int32, error) {
+	var width, height, viewBox string
+	for _, attr := range attrs {
+		switch attr.Name.Local {
+		case "width":
+			width = attr.Value
+		case "height":
+			height = attr.Value
+		case "viewBox":
+			viewBox = attr.Value
+		}
+	}
+
+	w, hasWidth := parseLength(width)
+	h, hasHeight := parseLength(height)
+	vw, vh, hasViewBox := parseViewBox(viewBox)
+
+	switch {
+	case hasWidth && hasHeight:
+	case hasWidth && hasViewBox:
+		h = w * vh / vw
+	case hasHeight && hasViewBox:
+		w = h * vw / vh
+	case hasViewBox:
+		w, h = vw, vh
+	default:
+		return 0, 0, ErrNoIntrinsicSize
+	}
+
+	if w > math.MaxInt32 || h > math.MaxInt32 {
+		return 0, 0, ErrCorrupt
+	}
+	return checkSize(int64(math.Round(w)), int64(math.Round(h)))
+}
+
+// parseLength absolute length in CSS pixels, false for percentages, auto and garbage
+func parseLength(s string) (float64, bool) {
+	m := svgLength.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, false
+	}
+	scale, ok := svgUnits[strings.ToLower(m[2])]
+	if !ok {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(m[1], 64)
+	if err != nil || v < 0 || math.IsInf(v, 0) {
+		return 0, false
+	}
+	return v * scale, true
+}
+
+// parseViewBox width and height of "min-x min-y width height"
+func parseViewBox(s string) (float64, float64, bool) {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\r' || r == '\n'
+	})
+	if len(fields) != 4 {
+		return 0, 0, false
+	}
+	w, errW := strconv.ParseFloat(fields[2], 64)
+	h, errH := strconv.ParseFloat(fields[3], 64)
+	if errW != nil || errH != nil || !(w > 0) || !(h > 0) || math.IsInf(w, 0) || math.IsInf(h, 0) {
+		return 0, 0, false
+	}
+	return w, h, true
+}
@@ -0,0 +1,136 @@
+package imageutils
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestSVGDimensions(t *testing.T) {
+	var samples = []struct {
+		src  string
+		w, h int32
+	}{
+		{"samples/file.svg", 794, 1123},  // millimeters, after DOCTYPE with internal subset
+		{"samples/file2.svg", 1201, 630}, // percentage width, size from viewBox
+	}
+
+	for _, sample := range samples {
+		data, err := ioutil.ReadFile(sample.src)
+		check(err)
+		if v := DetermineImageType(&data); v != "svg" {
+			t.Errorf("DetermineImageType (%s) returned %v, expected svg", sample.src, v)
+		}
+		if w, h, err := SVGDimensions(data); err != nil || w != sample.w || h != sample.h {
+			t.Errorf("SVGDimensions (%s) returned (%d, %d, %v), expected %d, %d", sample.src, w, h, err, sample.w, sample.h)
+		}
+	}
+}
+
+func TestSVGSize(t *testing.T) {
+	var samples = []struct {
+		svg  string
+		w, h int32
+		err  error
+	}{
+		{`<svg width="640" height="480">`, 640, 480, nil},
+		{`<svg width="64.6px" height="48.4">`, 65, 48, nil},
+		{`<svg width="1in" height="72pt">`, 96, 96, nil},
+		{`<svg width="10em" height="2.5cm">`, 160, 94, nil},
+		{`<svg width="400" viewBox="0 0 200 100">`, 400, 200, nil},
+		{`<svg height="50" viewBox="0 0 200 100">`, 100, 50, nil},
+		{`<svg width="100%" height="100%" viewBox="-10 -10 300 150">`, 300, 150, nil},
+		{`<svg width="100%" height="100%">`, 0, 0, ErrNoIntrinsicSize},
+		{`<svg width="auto">`, 0, 0, ErrNoIntrinsicSize},
+		{`<svg viewBox="0 0 0 100">`, 0, 0, ErrNoIntrinsicSize},
+		{`<!-- <svg width="1" height="1"> --><svg:svg xmlns:svg="http://www.w3.org/2000/svg" width="20" height="10">`, 20, 10, nil},
+		{`<?xml version="1.0"?><html><svg width="20" height="10"></svg></html>`, 0, 0, ErrFormat},
+		{`<?xml version="1.0"?><!-- no root`, 0, 0, ErrTruncated},
+	}
+
+	for _, sample := range samples {
+		w, h, err := SVGDimensions([]byte(sample.svg))
+		if !errors.Is(err, sample.err) || w != sample.w || h != sample.h {
+			t.Errorf("SVGDimensions(%q) returned (%d, %d, %v), expected %d, %d, %v", sample.svg, w, h, err, sample.w, sample.h, sample.err)
+		}
+	}
+}
+
+func TestIsSVG(t *testing.T) {
+	var samples = []struct {
+		data string
+		svg  bool
+	}{
+		{`<svg xmlns="http://www.w3.org/2000/svg">`, true},
+		{"\xEF\xBB\xBF\n<?xml version=\"1.0\"?>\n<!-- c -->\n<!DOCTYPE svg>\n<svg>", true},
+		{`<!DOCTYPE html><html><body><svg></svg>`, false},
+		{`<svgfoo>`, false},
+		{`<?xml version="1.0"?><!-- long comment that was cut <svg`, true},
+	}
+
+	for _, sample := range samples {
+		if v := isSVG([]byte(sample.data)); v != sample.svg {
+			t.Errorf("isSVG(%q) returned %v, expected %v", sample.data, v, sample.svg)
+		}
+	}
+}
+
+// illustratorSVG SVG as exported by Adobe Illustrator, root element after long DOCTYPE subset
+func illustratorSVG() []byte {
+	prolog := `<?xml version="1.0" encoding="utf-8"?>
+<!-- Generator: Adobe Illustrator 16.0.0, SVG Export Plug-In . SVG Version: 6.00 Build 0)  -->
+<!DOCTYPE svg PUBLIC "-//W3C//DTD SVG 1.1//EN" "http://www.w3.org/Graphics/SVG/1.1/DTD/svg11.dtd" [
+	<!ENTITY ns_extend "http://ns.adobe.com/Extensibility/1.0/">
+	<!ENTITY ns_ai "http://ns.adobe.com/AdobeIllustrator/10.0/">
+	<!ENTITY ns_graphs "http://ns.adobe.com/Graphs/1.0/">
+	<!ENTITY ns_vars "http://ns.adobe.com/Variables/1.0/">
+	<!ENTITY ns_imrep "http://ns.adobe.com/ImageReplacement/1.0/">
+	<!ENTITY ns_sfw "http://ns.adobe.com/SaveForWeb/1.0/">
+	<!ENTITY ns_custom "http://ns.adobe.com/GenericCustomNamespace/1.0/">
+	<!ENTITY ns_adobe_xpath "http://ns.adobe.com/XPath/1.0/">
+]>
+`
+	return []byte(prolog + `<svg version="1.1" xmlns="http://www.w3.org/2000/svg" x="0px" y="0px" width="120px" height="80px" viewBox="0 0 120 80">
+<rect width="120" height="80"/>
+</svg>
+`)
+}
+
+func TestSVGLongProlog(t *testing.T) {
+	data := illustratorSVG()
+	root := bytes.Index(data, []byte("<svg"))
+	if root <= minDetectBytes {
+		t.Fatalf("root element at %d, test needs prolog longer than %d bytes", root, minDetectBytes)
+	}
+
+	if v := DetermineImageType(&data); v != "svg" {
+		t.Errorf("DetermineImageType returned %q, expected svg", v)
+	}
+	want := Header{Type: "svg", Width: 120, Height: 80, Vector: true}
+	if header, err := DecodeHeader(data); err != nil || header != want {
+		t.Errorf("DecodeHeader returned %+v, %v, expected %+v", header, err, want)
+	}
+
+	// more data is needed while prolog or root element name is not complete
+	for _, n := range []int{minDetectBytes, root - 1, root + 2} {
+		if _, err := DecodeHeader(data[:n]); !errors.Is(err, ErrTruncated) {
+			t.Errorf("DecodeHeader of %d bytes returned %v, expected %v", n, err, ErrTruncated)
+		}
+	}
+
+	var h HeaderReader
+	if _, err := h.Decode(bytes.NewReader(data[:600])); !errors.Is(err, ErrTruncated) {
+		t.Errorf("Decode of first 600 bytes returned %v, expected %v", err, ErrTruncated)
+	}
+	if header, err := h.Decode(bytes.NewReader(data[600:])); err != nil || header != want {
+		t.Errorf("Decode returned %+v, %v, expected %+v", header, err, want)
+	}
+
+	// other XML after prolog is not an image
+	html := append(data[:root:root], []byte("<html>"+strings.Repeat(" ", 100)+"</html>")...)
+	if _, err := DecodeHeader(html); !errors.Is(err, ErrFormat) {
+		t.Errorf("DecodeHeader of XML document returned %v, expected %v", err, ErrFormat)
+	}
+}
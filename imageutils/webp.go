@@ -0,0 +1,75 @@
+package imageutils
+
+import (
+	"encoding/binary"
+)
+
+// WebPInfo header of WebP image
+type WebPInfo struct {
+	Width    int32
+	Height   int32
+	Format   string // VP8 (lossy), VP8L (lossless) or VP8X (extended)
+	Animated bool
+	Alpha    bool
+}
+
+// WEBPHeader read first chunk of WebP image
+func WEBPHeader(body []byte) (WebPInfo, error) {
+	var info WebPInfo
+
+	if len(body) < 12 {
+		return info, ErrTruncated
+	}
+	if string(body[:4]) != "RIFF" || string(body[8:12]) != "WEBP" {
+		return info, ErrFormat
+	}
+	if len(body) < 20 {
+		return info, ErrTruncated
+	}
+
+	chunk := string(body[12:16])
+	data := body[20:]
+	info.Format = chunk
+
+	switch chunk {
+	case "VP8 ":
+		// frame tag, start code, 14 bit width and height with 2 bit scale
+		if len(data) < 10 {
+			return info, ErrTruncated
+		}
+		if data[3] != 0x9D || data[4] != 0x01 || data[5] != 0x2A {
+			return info, ErrCorrupt
+		}
+		info.Format = "VP8"
+		info.Width = int32(binary.LittleEndian.Uint16(data[6:8]) & 0x3FFF)
+		info.Height = int32(binary.LittleEndian.Uint16(data[8:10]) & 0x3FFF)
+
+	case "VP8L":
+		// signature, then 14 bit width-1, 14 bit height-1, alpha hint and version
+		if len(data) < 5 {
+			return info, ErrTruncated
+		}
+		if data[0] != 0x2F {
+			return info, ErrCorrupt
+		}
+		bits := binary.LittleEndian.Uint32(data[1:5])
+		info.Width = int32(bits&0x3FFF) + 1
+		info.Height = int32((bits>>14)&0x3FFF) + 1
+		info.Alpha = (bits>>28)&1 == 1
+
+	case "VP8X":
+		// flags, reserved, 24 bit canvas width-1 and height-1
+		if len(data) < 10 {
+			return info, ErrTruncated
+		}
+		info.Animated = data[0]&0x02 != 0
+		info.Alpha = data[0]&0x10 != 0
+		info.Width = int32(uint32(data[4])|uint32(data[5])<<8|uint32(data[6])<<16) + 1
+		info.Height = int32(uint32(data[7])|uint32(data[8])<<8|uint32(data[9])<<16) + 1
+
+	default:
+		return info, ErrCorrupt
+	}
+
+	return info, nil
+}
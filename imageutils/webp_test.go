@@ -0,0 +1,46 @@
+package imageutils
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"testing"
+)
+
+func TestWEBPHeader(t *testing.T) {
+	sample, err := ioutil.ReadFile("samples/file.webp")
+	check(err)
+
+	var samples = []struct {
+		name string
+		data []byte
+		want WebPInfo
+	}{
+		{"samples/file.webp", sample, WebPInfo{Width: 521, Height: 450, Format: "VP8X", Alpha: true}},
+		// key frame tag, start code, 14 bit sizes with scale bits set
+		{"lossy", webpChunk("VP8 ", []byte{0x50, 0x0B, 0x00, 0x9D, 0x01, 0x2A, 0x80, 0x47, 0x38, 0xC4}), WebPInfo{Width: 1920, Height: 1080, Format: "VP8"}},
+		// 3000x2000 with alpha hint
+		{"lossless", webpChunk("VP8L", append([]byte{0x2F}, le32(2999|1999<<14|1<<28)...)), WebPInfo{Width: 3000, Height: 2000, Format: "VP8L", Alpha: true}},
+		// animation flag, 24 bit canvas 20000x300
+		{"extended", webpChunk("VP8X", []byte{0x02, 0, 0, 0, 0x1F, 0x4E, 0x00, 0x2B, 0x01, 0x00}), WebPInfo{Width: 20000, Height: 300, Format: "VP8X", Animated: true}},
+	}
+
+	for _, sample := range samples {
+		info, err := WEBPHeader(sample.data)
+		if err != nil || info != sample.want {
+			t.Errorf("WEBPHeader (%s) returned %+v, %v, expected %+v", sample.name, info, err, sample.want)
+		}
+		if w, h, err := WEBPDimensions(sample.data); err != nil || w != sample.want.Width || h != sample.want.Height {
+			t.Errorf("WEBPDimensions (%s) returned (%d, %d), expected %d, %d", sample.name, w, h, sample.want.Width, sample.want.Height)
+		}
+	}
+}
+
+func webpChunk(fourcc string, data []byte) []byte {
+	b := []byte("RIFF\x00\x00\x00\x00WEBP" + fourcc)
+	binary.LittleEndian.PutUint32(b[4:], uint32(12+len(data)))
+	return append(append(b, le32(uint32(len(data)))...), data...)
+}
+
+func le32(v uint32) []byte {
+	return binary.LittleEndian.AppendUint32(nil, v)
+}
@@ -0,0 +1,441 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	maxJobWorkers      = 5
+	maxQueuedJobs      = 1000
+	maxWebhookAttempts = 5
+	jobTTL             = time.Hour
+)
+
+// JobStatus state of async extraction
+type JobStatus string
+
+const (
+	JobQueued  JobStatus = "queued"
+	JobRunning JobStatus = "running"
+	JobDone    JobStatus = "done"
+	JobFailed  JobStatus = "failed"
+)
+
+// ErrJobNotFound returned by JobStore when there is no job with given id
+var ErrJobNotFound = errors.New("job not found")
+
+// Job async extraction, Result is set once job is finished
+type Job struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+	ExtractOptions
+	Webhook         string    `json:"webhook,omitempty"`
+	Status          JobStatus `json:"status"`
+	HTTPStatus      int       `json:"http_status,omitempty"`
+	Error           string    `json:"error,omitempty"`
+	Result          *Output   `json:"result,omitempty"`
+	WebhookAttempts int       `json:"webhook_attempts,omitempty"`
+	WebhookError    string    `json:"webhook_error,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// JobStore keeps jobs, implementations must be safe for concurrent use
+type JobStore interface {
+	Save(job Job) error
+	Get(id string) (Job, error)
+}
+
+// MemoryJobStore in-process JobStore, jobs are forgotten after ttl
+type MemoryJobStore struct {
+	mu   sync.RWMutex
+	ttl  time.Duration
+	jobs map[string]Job
+}
+
+// NewMemoryJobStore create store, ttl <= 0 keeps jobs forever
+func NewMemoryJobStore(ttl time.Duration) *MemoryJobStore {
+	return &MemoryJobStore{
+		ttl:  ttl,
+		jobs: make(map[string]Job),
+	}
+}
+
+// Save store copy of job
+func (s *MemoryJobStore) Save(job Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.jobs[job.ID] = job
+
+	// drop finished jobs past ttl
+	if s.ttl > 0 {
+		for id, j := range s.jobs {
+			if (j.Status == JobDone || j.Status == JobFailed) && time.Since(j.UpdatedAt) > s.ttl {
+				delete(s.jobs, id)
+			}
+		}
+	}
+	return nil
+}
+
+// Get return copy of job
+func (s *MemoryJobStore) Get(id string) (Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, ErrJobNotFound
+	}
+	return job, nil
+}
+
+// jobRequest queued job with copy of original request, used for headers
+type jobRequest struct {
+	id string
+	r  *http.Request
+}
+
+var (
+	jobStore JobStore = NewMemoryJobStore(jobTTL)
+	jobQueue          = make(chan jobRequest, maxQueuedJobs)
+
+	// webhookBackoff first delay between webhook attempts, doubled after each failure
+	webhookBackoff = time.Second
+	webhookClient  *http.Client
+
+	// jobsCtx of extractions and webhooks, cancelled when shutdown grace period ends
+	jobsCtx, cancelJobs = context.WithCancel(context.Background())
+)
+
+var (
+	jobWorkers sync.WaitGroup
+
+	// jobQueueMu guards sends on jobQueue against stopJobWorkers closing it
+	jobQueueMu     sync.RWMutex
+	jobQueueClosed bool
+)
+
+// startJobWorkers spin up workers consuming jobQueue
+func startJobWorkers(n int) {
+	for w := 1; w <= n; w++ {
+		jobWorkers.Add(1)
+		go func() {
+			defer jobWorkers.Done()
+			jobWorker(jobQueue)
+		}()
+	}
+}
+
+// stopJobWorkers close queue and wait for workers to finish queued jobs, running ones
+// are cancelled when ctx is done, call it after http server stopped accepting requests
+func stopJobWorkers(ctx context.Context) error {
+	jobQueueMu.Lock()
+	jobQueueClosed = true
+	close(jobQueue)
+	jobQueueMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		jobWorkers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		cancelJobs()
+		return ctx.Err()
+	}
+}
+
+// jobWorker run queued extractions
+func jobWorker(queue <-chan jobRequest) {
+	for req := range queue {
+		runJob(req)
+	}
+}
+
+// runJob extract url of job and call webhook
+func runJob(req jobRequest) {
+	job, err := jobStore.Get(req.id)
+	if err != nil {
+		log.Printf("Can't load job %s: %v", req.id, err)
+		return
+	}
+
+	job.Status = JobRunning
+	job.UpdatedAt = time.Now()
+	saveJob(job)
+
+	output, status := extractURL(job.URL, job.ExtractOptions, req.r)
+	observeExtraction("job", status)
+	job.HTTPStatus = status
+	if output.Success {
+		job.Status = JobDone
+	} else {
+		job.Status = JobFailed
+		job.Error = output.Message
+	}
+	job.Result = &output
+	job.UpdatedAt = time.Now()
+	saveJob(job)
+
+	if job.Webhook == "" {
+		return
+	}
+
+	attempts, err := deliverWebhook(req.r.Context(), job)
+	job.WebhookAttempts = attempts
+	if err != nil {
+		log.Printf("Webhook for job %s failed: %v", job.ID, err)
+		job.WebhookError = err.Error()
+	}
+	job.UpdatedAt = time.Now()
+	saveJob(job)
+}
+
+func saveJob(job Job) {
+	if err := jobStore.Save(job); err != nil {
+		log.Printf("Can't save job %s: %v", job.ID, err)
+	}
+}
+
+// signPayload HMAC-SHA256 of payload with WEBHOOK_SECRET, empty when secret is not set
+func signPayload(payload []byte) string {
+	secret := os.Getenv("WEBHOOK_SECRET")
+	if secret == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookStatusError non-2xx response of webhook
+type webhookStatusError struct {
+	code   int
+	status string
+}
+
+func (e webhookStatusError) Error() string {
+	return "webhook returned " + e.status
+}
+
+// retryable 5xx and 429 might succeed later, other 4xx are permanent
+func (e webhookStatusError) retryable() bool {
+	return e.code >= 500 || e.code == http.StatusTooManyRequests
+}
+
+// deliverWebhook POST job to webhook, retry network errors, 5xx and 429 with exponential
+// backoff until ctx is done
+func deliverWebhook(ctx context.Context, job Job) (int, error) {
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return 0, err
+	}
+	signature := signPayload(payload)
+
+	backoff := webhookBackoff
+	for attempt := 1; attempt <= maxWebhookAttempts; attempt++ {
+		err = postWebhook(ctx, job, payload, signature)
+		if err == nil {
+			return attempt, nil
+		}
+		// rejected by webhook, same payload won't be accepted later
+		var statusErr webhookStatusError
+		if errors.As(err, &statusErr) && !statusErr.retryable() {
+			return attempt, err
+		}
+		if attempt < maxWebhookAttempts {
+			select {
+			case <-ctx.Done():
+				return attempt, ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+	}
+	return maxWebhookAttempts, err
+}
+
+func postWebhook(ctx context.Context, job Job, payload []byte, signature string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.Webhook, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Prom-Job", job.ID)
+	if signature != "" {
+		req.Header.Set("X-Prom-Signature", signature)
+	}
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return webhookStatusError{code: resp.StatusCode, status: resp.Status}
+	}
+	return nil
+}
+
+// enqueueJob add job to queue without blocking, false when queue is full or closed
+func enqueueJob(req jobRequest) bool {
+	jobQueueMu.RLock()
+	defer jobQueueMu.RUnlock()
+
+	if jobQueueClosed {
+		return false
+	}
+
+	select {
+	case jobQueue <- req:
+		return true
+	default:
+		return false
+	}
+}
+
+// newJobID random hex id
+func newJobID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// JobInput body of POST /jobs/
+type JobInput struct {
+	URL string `json:"url"`
+	ExtractOptions
+	Webhook string `json:"webhook,omitempty"`
+}
+
+// writeJSON encode any value with given status
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		log.Printf("Failed to encode response: %v", err)
+	}
+}
+
+// handleJobs POST /jobs/ submit job, GET /jobs/{id} read its status
+func handleJobs(w http.ResponseWriter, r *http.Request) {
+	setCORS(w)
+
+	switch r.Method {
+	case http.MethodOptions:
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodPost:
+		submitJob(w, r)
+	case http.MethodGet:
+		id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/jobs"), "/")
+		if id == "" {
+			writeOutput(w, http.StatusBadRequest, Output{
+				Success: false,
+				Message: "Can't work without job id",
+			})
+			return
+		}
+		job, err := jobStore.Get(id)
+		if err != nil {
+			writeOutput(w, http.StatusNotFound, Output{
+				Success: false,
+				Message: err.Error(),
+			})
+			return
+		}
+		writeJSON(w, http.StatusOK, job)
+	default:
+		w.Header().Set("Allow", "GET, POST, OPTIONS")
+		rejectExtraction(w, "job", http.StatusMethodNotAllowed, "method", "Use POST to submit job or GET to read it")
+	}
+}
+
+func submitJob(w http.ResponseWriter, r *http.Request) {
+	var input JobInput
+	if err := json.NewDecoder(io.LimitReader(r.Body, cfg.MaxHTMLSize)).Decode(&input); err != nil {
+		rejectExtraction(w, "job", http.StatusBadRequest, "invalid_json", fmt.Sprintf("Failed to decode JSON: %v", err))
+		return
+	}
+
+	if input.URL == "" {
+		rejectExtraction(w, "job", http.StatusBadRequest, "missing_url", "Can't work without url")
+		return
+	}
+
+	if err := input.ExtractOptions.validate(); err != nil {
+		rejectExtraction(w, "job", http.StatusBadRequest, "invalid_options", err.Error())
+		return
+	}
+
+	if input.Webhook != "" {
+		u, err := url.Parse(input.Webhook)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			rejectExtraction(w, "job", http.StatusBadRequest, "invalid_webhook", "Webhook must be http or https url")
+			return
+		}
+		if err := urlGuard.CheckURL(u); err != nil {
+			rejectExtraction(w, "job", http.StatusForbidden, "blocked_webhook", fmt.Sprintf("Webhook not allowed: %v", err))
+			return
+		}
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		rejectExtraction(w, "job", http.StatusInternalServerError, "internal", fmt.Sprintf("Failed to create job: %v", err))
+		return
+	}
+
+	now := time.Now()
+	job := Job{
+		ID:             id,
+		URL:            input.URL,
+		ExtractOptions: input.ExtractOptions,
+		Webhook:        input.Webhook,
+		Status:         JobQueued,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if err := jobStore.Save(job); err != nil {
+		rejectExtraction(w, "job", http.StatusInternalServerError, "internal", fmt.Sprintf("Failed to save job: %v", err))
+		return
+	}
+
+	if !enqueueJob(jobRequest{id: id, r: r.Clone(jobsCtx)}) {
+		job.Status = JobFailed
+		job.Error = "Job queue is full"
+		saveJob(job)
+		observeRejection("job", http.StatusServiceUnavailable, "queue_full")
+		writeJSON(w, http.StatusServiceUnavailable, job)
+		return
+	}
+
+	w.Header().Set("Location", "/jobs/"+id)
+	writeJSON(w, http.StatusAccepted, job)
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestJobWithWebhook(t *testing.T) {
+	t.Setenv("WEBHOOK_SECRET", "secret")
+	webhookBackoff = time.Millisecond
+
+	ts := newPageServer()
+	defer ts.Close()
+
+	// fail first delivery to check retry
+	var calls int32
+	delivered := make(chan Job, 1)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write(body)
+		if expected := "sha256=" + hex.EncodeToString(mac.Sum(nil)); r.Header.Get("X-Prom-Signature") != expected {
+			t.Errorf("webhook got signature %v want %v", r.Header.Get("X-Prom-Signature"), expected)
+		}
+
+		var job Job
+		if err := json.Unmarshal(body, &job); err != nil {
+			t.Error(err)
+		}
+		delivered <- job
+	}))
+	defer hook.Close()
+
+	payload := fmt.Sprintf(`{"url": "%s/page", "webhook": "%s"}`, ts.URL, hook.URL)
+	req, err := http.NewRequest("POST", "/jobs/", strings.NewReader(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(handleJobs)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusAccepted {
+		t.Fatalf("handler returned wrong status code: got %v want %v",
+			status, http.StatusAccepted)
+	}
+
+	var submitted Job
+	if err := json.Unmarshal(rr.Body.Bytes(), &submitted); err != nil {
+		t.Fatal(err)
+	}
+	if submitted.ID == "" || submitted.Status != JobQueued {
+		t.Fatalf("handler returned unexpected job: %+v", submitted)
+	}
+
+	// run queued job in place of worker
+	runJob(<-jobQueue)
+
+	select {
+	case job := <-delivered:
+		if job.ID != submitted.ID || job.Status != JobDone || job.Result == nil || job.Result.Title != "/page" {
+			t.Errorf("webhook got unexpected job: %+v", job)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("webhook was not called")
+	}
+
+	req, err = http.NewRequest("GET", "/jobs/"+submitted.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	var job Job
+	if err := json.Unmarshal(rr.Body.Bytes(), &job); err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != JobDone || job.WebhookAttempts != 2 || job.WebhookError != "" {
+		t.Errorf("handler returned unexpected job: %+v", job)
+	}
+}
+
+func TestJobNotFound(t *testing.T) {
+	req, err := http.NewRequest("GET", "/jobs/missing", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(handleJobs)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusNotFound {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusNotFound)
+	}
+}
+
+func TestDeliverWebhookCancelled(t *testing.T) {
+	webhookBackoff = time.Hour
+	defer func() { webhookBackoff = time.Millisecond }()
+
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer hook.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	attempts, err := deliverWebhook(ctx, Job{ID: "cancelled", Webhook: hook.URL})
+	if attempts != 1 || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("deliverWebhook = %d, %v, want 1 attempt and %v", attempts, err, context.DeadlineExceeded)
+	}
+}
+
+func TestDeliverWebhookPermanentError(t *testing.T) {
+	webhookBackoff = time.Millisecond
+
+	tests := map[int]int{
+		http.StatusBadRequest:          1,
+		http.StatusUnauthorized:        1,
+		http.StatusNotFound:            1,
+		http.StatusGone:                1,
+		http.StatusTooManyRequests:     maxWebhookAttempts,
+		http.StatusInternalServerError: maxWebhookAttempts,
+	}
+	for status, expected := range tests {
+		var calls int32
+		hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(status)
+		}))
+
+		attempts, err := deliverWebhook(context.Background(), Job{ID: "rejected", Webhook: hook.URL})
+		hook.Close()
+		if err == nil || attempts != expected || int(calls) != expected {
+			t.Errorf("deliverWebhook with %d = %d attempts, %d calls, %v, want %d", status, attempts, calls, err, expected)
+		}
+	}
+}
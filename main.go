@@ -2,116 +2,240 @@ package main
 
 import (
 	"bytes"
-	"crypto/tls"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"io"
 
+	"github.com/slav123/prom/cache"
+	"github.com/slav123/prom/config"
 	"github.com/slav123/prom/htmlutils"
 	"github.com/slav123/prom/imageutils"
+	"github.com/slav123/prom/netguard"
 
 	"log"
 	"net/http"
+	neturl "net/url"
 	"strconv"
 	"strings"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/denisbrodbeck/striphtmltags"
-)
-
-const (
-	maxWorkers = 5
-	port       = 9999
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 var (
-	promImage     string
-	maxDimensions int
+	// cfg effective configuration, see config.Load
+	cfg = config.Default()
 )
 
 // ImageResult holds information about processed image
 type ImageResult struct {
-	URL    string
-	Width  int32
-	Height int32
-	Area   int
+	URL      string `json:"url"`
+	Type     string `json:"type,omitempty"`
+	Width    int32  `json:"width"`
+	Height   int32  `json:"height"`
+	Size     int64  `json:"size,omitempty"` // whole file size, from Content-Range
+	Animated bool   `json:"animated,omitempty"`
+	Alpha    bool   `json:"alpha,omitempty"`
+	Vector   bool   `json:"vector,omitempty"` // SVG, width and height are 0 when it has no intrinsic size
+	Frames   int    `json:"frames,omitempty"` // animations only, lower bound when file is bigger than cfg.MaxImageBytes
+	Loops    int    `json:"loops,omitempty"`  // times animation is played, missing when it plays forever
+	Duration int64  `json:"duration_ms,omitempty"`
+	Area     int    `json:"-"`
 }
 
 // GetDimensions get image dimensions
 func GetDimensions(id int, jobs <-chan string, results chan<- ImageResult, r *http.Request) {
+	ctx := r.Context()
+
 	for url := range jobs {
-		result := ImageResult{
-			URL: url,
+		imageQueueDepth.Dec()
+
+		// caller went away, drain remaining jobs without probing
+		if ctx.Err() != nil {
+			results <- ImageResult{URL: url}
+			continue
 		}
 
-		fmt.Println("worker", id, "started job", url)
+		// same logos and hero images show up on many pages
+		if cached, ok := imageCache.Get(url); ok {
+			results <- cached
+			continue
+		}
 
-		// header size to get
-		min := 0
-		max := 51200
+		fmt.Println("worker", id, "started job", url)
 
-		// get file
-		client := &http.Client{}
-		req, err := http.NewRequest(http.MethodGet, url, nil)
+		result, err := probeImage(ctx, url)
 		if err != nil {
-			log.Printf("error creating request for %s: %s", url, err.Error())
-			results <- result
-			continue
+			imageProbeFailuresTotal.Inc()
+			log.Print(err.Error())
 		}
 
-		rangeHeader := "bytes=" + strconv.Itoa(min) + "-" + strconv.Itoa(max-1)
-		req.Header.Add("Range", rangeHeader)
-		req.Header.Add("User-agent", "Googlebot-Image/1.0")
-		resp, err := client.Do(req)
+		// don't remember failures caused by cancelled request
+		if ctx.Err() == nil {
+			imageCache.Set(result, err == nil && result.Area > 0)
+		}
+
+		results <- result
+	}
+}
+
+// firstProbeBytes size of first range request, enough for PNG, GIF, WebP and most JPEG
+// headers. Follow-up ranges grow 4 times up to cfg.MaxImageBytes
+const firstProbeBytes = 4096
+
+// probeImage download image header and read dimensions, asking for more bytes only
+// when header is longer than what was fetched. GIF, WebP and PNG are read further
+// to count frames of animation
+func probeImage(ctx context.Context, url string) (ImageResult, error) {
+	result := ImageResult{
+		URL: url,
+	}
 
+	reader := imageutils.HeaderReader{Limit: int(cfg.MaxImageBytes), Frames: true}
+	size := firstProbeBytes
+
+	for {
+		start := reader.Len()
+		resp, err := fetchImageRange(ctx, url, start, min(start+size, reader.Limit)-1)
 		if err != nil {
-			log.Printf("error pulling %s: %s", url, err.Error())
-			results <- result
-			continue
+			return result, err
 		}
 
-		body, err := io.ReadAll(resp.Body)
+		// server ignoring Range sends whole file, it is closed as soon as header is read
+		header, err := reader.Decode(resp.Body)
 		resp.Body.Close()
 
-		if err != nil {
-			log.Printf("error reading %s: %s", url, err.Error())
-			results <- result
+		if start == 0 {
+			result.Size = imageSize(resp)
+		}
+
+		more := errors.Is(err, imageutils.ErrTruncated) &&
+			resp.StatusCode == http.StatusPartialContent &&
+			reader.Len() > start && reader.Len() < reader.Limit &&
+			(result.Size == 0 || int64(reader.Len()) < result.Size)
+		if more {
+			size *= 4
 			continue
 		}
 
-		// determine image type
-		fileType := imageutils.DetermineImageType(&body)
-
-		// get dimensions
-		switch fileType {
-		case "png":
-			result.Width, result.Height = imageutils.PNGDimensions(body)
-		case "jpg":
-			result.Width, result.Height = imageutils.JPGDimensions(body)
-		case "gif":
-			result.Width, result.Height = imageutils.GIFDimensions(body)
-		case "webp":
-			result.Width, result.Height = imageutils.WEBPDimensions(body)
-		case "svg":
-			result.Width, result.Height = imageutils.SVGDimensions(body)
+		return measureImage(result, header, err)
+	}
+}
+
+// fetchImageRange request bytes from start to end of image, follow-up requests must get
+// exactly requested range
+func fetchImageRange(ctx context.Context, url string, start, end int) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request for %s: %w", url, err)
+	}
+
+	if err := urlGuard.CheckURL(req.URL); err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Range", "bytes="+strconv.Itoa(start)+"-"+strconv.Itoa(end))
+	req.Header.Add("User-agent", cfg.ImageUserAgent)
+	resp, err := imageClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error pulling %s: %w", url, err)
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		resp.Body.Close()
+		return nil, fmt.Errorf("error pulling %s: %s", url, resp.Status)
+	}
+
+	if start > 0 && (resp.StatusCode != http.StatusPartialContent ||
+		!strings.HasPrefix(resp.Header.Get("Content-Range"), "bytes "+strconv.Itoa(start)+"-")) {
+		resp.Body.Close()
+		return nil, fmt.Errorf("error pulling %s: range %d-%d not served", url, start, end)
+	}
+
+	return resp, nil
+}
+
+// measureImage fill result with decoded header, unknown formats are not an error and
+// neither are vector images without size or animations with frames beyond limit
+func measureImage(result ImageResult, header imageutils.Header, err error) (ImageResult, error) {
+	result.Type = header.Type
+	if header.Type == "" {
+		imageProbesTotal.WithLabelValues("unknown").Inc()
+		if errors.Is(err, imageutils.ErrFormat) || errors.Is(err, imageutils.ErrTruncated) {
+			return result, nil
 		}
+	} else {
+		imageProbesTotal.WithLabelValues(header.Type).Inc()
+	}
 
-		result.Area = int(result.Width * result.Height)
-		fmt.Printf("url: %s, width: %d, height: %d, area: %d\n", 
-			result.URL, result.Width, result.Height, result.Area)
+	result.Vector = header.Vector
+	if err != nil && !errors.Is(err, imageutils.ErrNoIntrinsicSize) && !errors.Is(err, imageutils.ErrFramesTruncated) {
+		return result, fmt.Errorf("error measuring %s %s: %w", header.Type, result.URL, err)
+	}
 
-		results <- result
+	result.Width, result.Height = header.Width, header.Height
+	result.Animated, result.Alpha = header.Animated, header.Alpha
+	result.Frames, result.Loops, result.Duration = header.Frames, header.Loops, header.Duration.Milliseconds()
+	result.Area = int(result.Width) * int(result.Height)
+	fmt.Printf("url: %s, width: %d, height: %d, area: %d\n",
+		result.URL, result.Width, result.Height, result.Area)
+
+	return result, nil
+}
+
+// imageSize total size of image from "Content-Range: bytes 0-51199/123456",
+// or Content-Length when server ignored Range
+func imageSize(resp *http.Response) int64 {
+	if cr := resp.Header.Get("Content-Range"); cr != "" {
+		if i := strings.LastIndex(cr, "/"); i >= 0 {
+			if size, err := strconv.ParseInt(cr[i+1:], 10, 64); err == nil {
+				return size
+			}
+		}
+		return 0
+	}
+	if resp.StatusCode == http.StatusOK && resp.ContentLength > 0 {
+		return resp.ContentLength
 	}
+	return 0
 }
 
-// GetAllImages on the website
-func GetAllImages(re io.Reader, url string, r *http.Request) string {
+// GetAllImages on the website, probe them together with meta candidates and return
+// candidates best first, content is readability output used to tell which images belong to article
+func GetAllImages(re io.Reader, url, content string, meta []ScoredImage, r *http.Request) []ScoredImage {
 	// get all images url
-	images := htmlutils.ScrapeImg(re, url)
+	inline := htmlutils.ScrapeImages(re, url)
+
+	// meta images go first, inline image with same url is skipped
+	images := make([]string, 0, len(meta)+len(inline))
+	seen := make(map[string]bool)
+	for _, m := range meta {
+		if !seen[m.URL] {
+			seen[m.URL] = true
+			images = append(images, m.URL)
+		}
+	}
+	for _, image := range inline {
+		if seen[image.URL] {
+			continue
+		}
+		seen[image.URL] = true
+		// declared size too small to ever be lead image, don't waste a probe
+		if image.Width > 0 && image.Height > 0 &&
+			(image.Width < cfg.Scoring.MinWidth || image.Height < cfg.Scoring.MinHeight) {
+			continue
+		}
+		images = append(images, image.URL)
+	}
 
 	// count images
 	imagesCount := len(images)
@@ -121,31 +245,77 @@ func GetAllImages(re io.Reader, url string, r *http.Request) string {
 	results := make(chan ImageResult, imagesCount)
 
 	// spin up workers
-	for w := 1; w <= maxWorkers; w++ {
+	for w := 1; w <= cfg.Workers; w++ {
 		go GetDimensions(w, jobs, results, r)
 	}
 
 	// send jobs
+	imageQueueDepth.Add(float64(imagesCount))
 	for j := 0; j < imagesCount; j++ {
 		jobs <- images[j]
 	}
 	close(jobs)
 
 	// collect all results
-	var largestImage ImageResult
+	probed := make(map[string]ImageResult, imagesCount)
 	for a := 0; a < imagesCount; a++ {
 		result := <-results
-		if result.Area > largestImage.Area {
-			largestImage = result
+		probed[result.URL] = result
+	}
+
+	// images which survived readability
+	inContent := make(map[string]bool)
+	for _, src := range htmlutils.ScrapeImg(strings.NewReader(content), url) {
+		inContent[src] = true
+	}
+
+	candidates := make([]ScoredImage, 0, imagesCount)
+	seen = make(map[string]bool)
+	for _, m := range meta {
+		if seen[m.URL] {
+			continue
 		}
+		seen[m.URL] = true
+		candidate := m
+		candidate.ImageResult = probed[m.URL]
+		candidate.URL = m.URL
+		candidate.Position = -1
+		candidate.InContent = inContent[m.URL]
+		candidate.Score = scoreImage(candidate, "", len(inline), cfg.Scoring)
+		candidates = append(candidates, candidate)
 	}
+	for _, image := range inline {
+		if seen[image.URL] {
+			continue
+		}
+		seen[image.URL] = true
+		result, wasProbed := probed[image.URL]
+		candidate := ScoredImage{
+			ImageResult: result,
+			Source:      image.Source,
+			Alt:         image.Alt,
+			Position:    image.Position,
+			InContent:   inContent[image.URL],
+		}
+		candidate.URL = image.URL
+		// probe skipped for small declared size, trust markup. Failed probe stays at zero
+		// size, declared size of missing or blocked image says nothing
+		if !wasProbed && image.Width > 0 && image.Height > 0 {
+			candidate.Width, candidate.Height = int32(image.Width), int32(image.Height)
+			candidate.Area = image.Width * image.Height
+		}
+		candidate.Score = scoreImage(candidate, image.Hints, len(inline), cfg.Scoring)
+		candidates = append(candidates, candidate)
+	}
+	rankImages(candidates)
 
-	if largestImage.URL != "" {
-		fmt.Printf("Largest image found: %s (dimensions: %dx%d, area: %d)\n", 
-			largestImage.URL, largestImage.Width, largestImage.Height, largestImage.Area)
+	if len(candidates) > 0 && candidates[0].Score.Eligible {
+		lead := candidates[0]
+		fmt.Printf("Lead image found: %s (dimensions: %dx%d, score: %.2f)\n",
+			lead.URL, lead.Width, lead.Height, lead.Score.Total)
 	}
 
-	return largestImage.URL
+	return candidates
 }
 
 type Output struct {
@@ -154,7 +324,12 @@ type Output struct {
 	Title         string `json:"title"`
 	Description   string `json:"description"`
 	Keywords      string `json:"keywords"`
+	Headline      string `json:"headline"`
+	Author        string `json:"author"`
+	Publisher     string `json:"publisher"`
+	Section       string `json:"section"`
 	DatePublished string `json:"date_published"`
+	DateModified  string `json:"date_modified"`
 	LastModified  string `json:"last_modified"`
 	LeadImageURL  string `json:"lead_image_url"`
 	Dek           string `json:"dek"`
@@ -162,35 +337,93 @@ type Output struct {
 	Domain        string `json:"domain"`
 	Excerpt       string `json:"excerpt"`
 	Content       string `json:"content"`
+
+	LeadImageScore *ImageScore            `json:"lead_image_score,omitempty"`
+	Images         []ScoredImage          `json:"images,omitempty"`
+	OpenGraph      *htmlutils.OpenGraph   `json:"og,omitempty"`
+	Twitter        *htmlutils.TwitterCard `json:"twitter,omitempty"`
 }
 
 type StatusResponse struct {
-	Alive   bool   `json:"alive"`
-	Version string `json:"version"`
+	Alive      bool            `json:"alive"`
+	Version    string          `json:"version"`
+	ImageCache ImageCacheStats `json:"image_cache"`
+	Config     config.Config   `json:"config"`
 }
 
 // keep minVersion for static builds
 var minVersion string
 
 func main() {
+	var err error
+	cfg, err = config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatal("Config: ", err)
+	}
+
 	log.Printf("Build: %s\n", minVersion)
-	log.Printf("Listening on port: %d", port)
+	log.Printf("Listening on: %s", cfg.Listen)
+
+	if err := initGuard(); err != nil {
+		log.Fatal("SSRF guard: ", err)
+	}
+
+	if err := initPageCache(); err != nil {
+		log.Fatal("Cache: ", err)
+	}
+
+	initImageMetrics()
+	initBatchPool()
+	startJobWorkers(maxJobWorkers)
 
 	http.HandleFunc("/status", handleStatus)
 	http.HandleFunc("/url/", handleExtract)
+	http.HandleFunc("/html/", handleExtractHTML)
+	http.HandleFunc("/batch/", handleBatch)
+	http.HandleFunc("/jobs/", handleJobs)
+	http.Handle("/metrics", promhttp.Handler())
 	http.HandleFunc("/", handleStatus)
 
-	err := http.ListenAndServe(fmt.Sprintf(":%d", port), nil)
-	if err != nil {
+	server := &http.Server{Addr: cfg.Listen}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+		<-ctx.Done()
+		stop()
+		log.Printf("Shutting down, grace period: %s", time.Duration(cfg.ShutdownGrace))
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.ShutdownGrace))
+		defer cancel()
+
+		// stop accepting requests and wait for in-flight ones, then finish queued jobs
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Shutdown: %v", err)
+		}
+		if err := stopJobWorkers(shutdownCtx); err != nil {
+			log.Printf("Shutdown jobs: %v", err)
+		}
+	}()
+
+	err = server.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Fatal("ListenAndServe: ", err)
 	}
+
+	// wait for shutdown to finish
+	<-shutdownDone
 }
 
 // handleStatus display status with version
 func handleStatus(w http.ResponseWriter, r *http.Request) {
 	response := StatusResponse{
-		Alive:   true,
-		Version: minVersion,
+		Alive:      true,
+		Version:    minVersion,
+		ImageCache: imageCache.Stats(),
+		Config:     cfg,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -202,104 +435,294 @@ func handleStatus(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// handleExtract process extraction
-func handleExtract(w http.ResponseWriter, r *http.Request) {
+// setCORS headers shared by extraction endpoints
+func setCORS(w http.ResponseWriter) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Access-Control-Allow-Methods", "*")
 	w.Header().Set("Access-Control-Allow-Headers", "Origin, X-Requested-With, Content-Type, Accept, Authorization")
+}
 
-	var result Output
-	result.Success = false // default to false
+// writeOutput encode result with given status
+func writeOutput(w http.ResponseWriter, status int, result Output) {
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(&result); err != nil {
+		log.Printf("Failed to encode response: %v", err)
+	}
+}
+
+// rejectExtraction write error output and count it as rejected request of endpoint
+func rejectExtraction(w http.ResponseWriter, endpoint string, status int, reason, message string) {
+	observeRejection(endpoint, status, reason)
+	writeOutput(w, status, Output{
+		Success: false,
+		Message: message,
+	})
+}
+
+// handleExtract process extraction
+func handleExtract(w http.ResponseWriter, r *http.Request) {
+	setCORS(w)
 
 	url := r.URL.Query().Get("url")
 	if url == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(Output{
-			Success: false,
-			Message: "Can't work without url",
-		})
+		rejectExtraction(w, "url", http.StatusBadRequest, "missing_url", "Can't work without url")
 		return
 	}
 
-	proxy := r.URL.Query().Get("proxy")
-	if proxy == "own" {
-		url = fmt.Sprintf("%s%s", os.Getenv("PROXY_OWN"), url)
+	opts, err := optionsFromQuery(r.URL.Query())
+	if err != nil {
+		rejectExtraction(w, "url", http.StatusBadRequest, "invalid_options", err.Error())
+		return
+	}
+
+	result, status := extractURL(url, opts, r)
+	observeExtraction("url", status)
+	writeOutput(w, status, result)
+}
+
+// ExtractOptions per request options of extraction
+type ExtractOptions struct {
+	Proxy  string `json:"proxy,omitempty"`
+	Images int    `json:"images,omitempty"` // return up to Images ranked candidates, 0 disables
+}
+
+// maxImages upper bound of ranked candidates returned in output
+const maxImages = 50
+
+// validate options coming from query or JSON body
+func (o ExtractOptions) validate() error {
+	if o.Images < 0 || o.Images > maxImages {
+		return fmt.Errorf("images must be a non-negative integer up to %d", maxImages)
+	}
+	return nil
+}
+
+// optionsFromQuery read ?proxy= and ?images=
+func optionsFromQuery(q neturl.Values) (ExtractOptions, error) {
+	opts := ExtractOptions{
+		Proxy: q.Get("proxy"),
+	}
+
+	if images := q.Get("images"); images != "" {
+		n, err := strconv.Atoi(images)
+		if err != nil {
+			n = -1
+		}
+		opts.Images = n
+	}
+	return opts, opts.validate()
+}
+
+// HTMLInput body of /html/ request sent as JSON
+type HTMLInput struct {
+	HTML   string `json:"html"`
+	URL    string `json:"url"`
+	Images int    `json:"images,omitempty"`
+}
+
+// handleExtractHTML process extraction of HTML posted by caller, either as raw
+// body with optional ?url= base or as JSON HTMLInput
+func handleExtractHTML(w http.ResponseWriter, r *http.Request) {
+	setCORS(w)
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
 	}
 
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST, OPTIONS")
+		rejectExtraction(w, "html", http.StatusMethodNotAllowed, "method", "Use POST with HTML body")
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, cfg.MaxHTMLSize))
+	if err != nil {
+		rejectExtraction(w, "html", http.StatusRequestEntityTooLarge, "body_too_large", fmt.Sprintf("Failed to read body: %v", err))
+		return
 	}
 
-	client := &http.Client{
-		Timeout:   time.Second * 10,
-		Transport: tr,
+	baseURL := r.URL.Query().Get("url")
+	opts, err := optionsFromQuery(r.URL.Query())
+	if err != nil {
+		rejectExtraction(w, "html", http.StatusBadRequest, "invalid_options", err.Error())
+		return
+	}
+	opts.Proxy = ""
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var input HTMLInput
+		if err := json.Unmarshal(body, &input); err != nil {
+			rejectExtraction(w, "html", http.StatusBadRequest, "invalid_json", fmt.Sprintf("Failed to decode JSON: %v", err))
+			return
+		}
+		body = []byte(input.HTML)
+		if input.URL != "" {
+			baseURL = input.URL
+		}
+		if input.Images != 0 {
+			opts.Images = input.Images
+		}
+		if err := opts.validate(); err != nil {
+			rejectExtraction(w, "html", http.StatusBadRequest, "invalid_options", err.Error())
+			return
+		}
+	}
+
+	if len(bytes.TrimSpace(body)) == 0 {
+		rejectExtraction(w, "html", http.StatusBadRequest, "missing_html", "Can't work without html")
+		return
+	}
+
+	pageBodyBytes.Observe(float64(len(body)))
+
+	result := extractHTML(body, baseURL, baseURL, opts, r)
+	if !result.Success {
+		observeExtraction("html", http.StatusUnprocessableEntity)
+		writeOutput(w, http.StatusUnprocessableEntity, result)
+		return
+	}
+	observeExtraction("html", http.StatusOK)
+	writeOutput(w, http.StatusOK, result)
+}
+
+// extractURL fetch page and run extraction, returns output and http status
+func extractURL(url string, opts ExtractOptions, r *http.Request) (Output, int) {
+	if opts.Proxy == "own" {
+		url = fmt.Sprintf("%s%s", os.Getenv("PROXY_OWN"), url)
 	}
 
 	// get page
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, url, nil)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(Output{
+		log.Printf("Can't create request: %s", err.Error())
+		return Output{
 			Success: false,
 			Message: fmt.Sprintf("Failed to create request: %v", err),
-		})
-		log.Printf("Can't create request: %s", err.Error())
-		return
+		}, http.StatusBadRequest
+	}
+
+	// before cache lookup, so blocked URLs are never served from cache
+	if err := urlGuard.CheckURL(req.URL); err != nil {
+		log.Printf("Blocked request: %s", err.Error())
+		return Output{
+			Success: false,
+			Message: fmt.Sprintf("Failed to create request: %v", err),
+		}, http.StatusForbidden
+	}
+
+	// candidates and language forwarded to origin change output, keep them apart
+	language := strings.TrimSpace(r.Header.Get("Accept-Language"))
+	cacheKey := cache.NormalizeURL(url)
+	if opts.Images > 0 {
+		cacheKey += " images=" + strconv.Itoa(opts.Images)
+	}
+	if language != "" {
+		cacheKey += " lang=" + strings.ToLower(language)
+	}
+	cached, cachedOutput, hasCached := loadCached(cacheKey)
+	if hasCached && cached.Fresh(time.Duration(cfg.Cache.TTL)) {
+		return cachedOutput, http.StatusOK
 	}
 
 	// pretend to be google bot ;)
-	req.Header.Add("User-agent", "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)")
-	req.Header.Add("Accept-Language", r.Header.Get("Accept-Language"))
-	resp, err := client.Do(req)
+	req.Header.Add("User-agent", cfg.PageUserAgent)
+	req.Header.Add("Accept-Language", language)
+
+	// stale entry, ask origin if it changed
+	if hasCached && cached.Revalidatable() {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	start := time.Now()
+	resp, err := pageClient.Do(req)
 	if err != nil {
-		w.WriteHeader(http.StatusBadGateway)
-		json.NewEncoder(w).Encode(Output{
+		if errors.Is(err, netguard.ErrBlocked) {
+			log.Printf("Blocked request: %s", err.Error())
+			return Output{
+				Success: false,
+				Message: fmt.Sprintf("Failed to fetch page: %v", err.Error()),
+			}, http.StatusForbidden
+		}
+		log.Printf("Can't read page error: %s", err.Error())
+		return Output{
 			Success: false,
 			Message: fmt.Sprintf("Failed to fetch page: %v", err.Error()),
-		})
-		log.Printf("Can't read page error: %s", err.Error())
-		return
+		}, http.StatusBadGateway
 	}
 
 	if resp == nil {
-		w.WriteHeader(http.StatusBadGateway)
-		json.NewEncoder(w).Encode(Output{
+		return Output{
 			Success: false,
 			Message: "Empty response received",
-		})
-		return
+		}, http.StatusBadGateway
 	}
 	defer resp.Body.Close()
 
-	// we do try to extract image
+	if resp.StatusCode == http.StatusNotModified && hasCached {
+		touchCached(cached)
+		return cachedOutput, http.StatusOK
+	}
+
+	// get actual URL of page
 	var urlStr string
 	if resp.Request != nil {
 		urlStr = resp.Request.URL.String()
 	} else {
-		urlStr = r.URL.String()
+		urlStr = url
 	}
 
-	// get actual URL of page
-	result.URL = urlStr
-	result.Domain = htmlutils.DomainURL(result.URL)
-
-	body, err := io.ReadAll(resp.Body)
+	body, err := io.ReadAll(io.LimitReader(resp.Body, cfg.MaxBodySize))
 	if err != nil {
-		w.WriteHeader(http.StatusBadGateway)
-		json.NewEncoder(w).Encode(Output{
+		log.Printf("Failed to read body of: %s, error: %v", urlStr, err)
+		return Output{
 			Success: false,
 			Message: fmt.Sprintf("Failed to read body: %v", err),
-		})
-		log.Printf("Failed to read body of: %s, error: %v", result.URL, err)
-		return
+		}, http.StatusBadGateway
+	}
+	pageFetchSeconds.Observe(time.Since(start).Seconds())
+	pageBodyBytes.Observe(float64(len(body)))
+
+	result := extractHTML(body, urlStr, url, opts, r)
+	if !result.Success {
+		return result, http.StatusUnprocessableEntity
+	}
+
+	if lastMod := resp.Header.Get("Last-Modified"); lastMod != "" {
+		result.LastModified = lastMod
+	}
+
+	if resp.StatusCode == http.StatusOK {
+		storeCached(cacheKey, result, resp.Header)
+	}
+
+	return result, http.StatusOK
+}
+
+// extractHTML run extraction pipeline on already downloaded page,
+// pageURL is the actual page address, baseURL is used to resolve images
+func extractHTML(body []byte, pageURL, baseURL string, opts ExtractOptions, r *http.Request) Output {
+	var result Output
+	result.Success = false // default to false
+
+	if pageURL != "" {
+		result.URL = pageURL
+		result.Domain = htmlutils.DomainURL(result.URL)
 	}
 
 	// Process the content
-	bodyReader := bytes.NewReader(body)
-	doc, err := goquery.NewDocumentFromReader(bodyReader)
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
 	if err != nil {
-		log.Fatal(err)
+		result.Message = fmt.Sprintf("Failed to parse page: %v", err)
+		return result
 	}
 
 	result.Title = htmlutils.SearchForTitleFromDoc(doc)
@@ -307,8 +730,30 @@ func handleExtract(w http.ResponseWriter, r *http.Request) {
 	result.Description, err = htmlutils.SearchForMetaTag(bytes.NewReader(body), "description")
 	result.Keywords, err = htmlutils.SearchForMetaTag(bytes.NewReader(body), "keywords")
 
-	if lastMod := resp.Header.Get("Last-Modified"); lastMod != "" {
-		result.LastModified = lastMod
+	var schemaImage string
+
+	// OpenGraph and Twitter Card
+	if og := htmlutils.SearchForOpenGraphFromDoc(doc); len(og.Properties) > 0 {
+		result.OpenGraph = &og
+	}
+	if card := htmlutils.SearchForTwitterCardFromDoc(doc); len(card.Properties) > 0 {
+		result.Twitter = &card
+	}
+
+	// schema.org JSON-LD
+	if article, ok := htmlutils.SearchForArticleFromDoc(doc); ok {
+		result.Headline = article.Headline
+		result.Author = article.Author
+		result.Publisher = article.Publisher
+		result.Section = article.Section
+		result.DateModified = article.DateModified
+		if result.DatePublished == "" {
+			result.DatePublished = article.DatePublished
+		}
+		if result.Title == "" {
+			result.Title = article.Headline
+		}
+		schemaImage = article.Image
 	}
 
 	result.Content, err = htmlutils.ReadBodyFromDoc(doc)
@@ -319,36 +764,55 @@ func handleExtract(w http.ResponseWriter, r *http.Request) {
 	result.Excerpt = htmlutils.Excerpt(result.Dek)
 
 	// lead image - first try to get it from meta
-	promImage, err = htmlutils.SearchForMetaImage(bytes.NewReader(body))
+	source := "meta"
+	promImage, err := htmlutils.SearchForMetaImageFromDoc(doc)
 	if err != nil {
 		slog.Error(err.Error())
 	}
 
 	if promImage == "" {
-		promImage = GetAllImages(bytes.NewReader(body), url, r)
+		source = "schema"
+		promImage = schemaImage
+	}
+
+	if promImage == "" && result.Twitter != nil {
+		source = "twitter"
+		promImage = result.Twitter.Image
+	}
+
+	// inline images are probed when there is no meta image or caller wants candidates
+	var candidates []ScoredImage
+	if promImage == "" || opts.Images > 0 {
+		candidates = GetAllImages(bytes.NewReader(body), baseURL, result.Content, metaCandidates(&result, promImage, schemaImage, baseURL), r)
+	}
+
+	if opts.Images > 0 {
+		result.Images = candidates
+		if len(result.Images) > opts.Images {
+			result.Images = result.Images[:opts.Images]
+		}
+	}
+
+	if promImage == "" {
+		source = "none"
+		if len(candidates) > 0 && candidates[0].Score.Eligible {
+			source = "scan"
+			promImage = candidates[0].URL
+			result.LeadImageScore = &candidates[0].Score
+		}
 	} else {
 		// remove proxy url from image
-		if proxy == "own" {
-			promImage = strings.Replace(url, os.Getenv("PROXY_OWN"), "", 1)
+		if opts.Proxy == "own" {
+			promImage = strings.Replace(baseURL, os.Getenv("PROXY_OWN"), "", 1)
 		}
-		promImage = htmlutils.GetBaseUrlString(promImage, url)
+		promImage = htmlutils.GetBaseUrlString(promImage, baseURL)
 	}
 	result.LeadImageURL = promImage
+	leadImageSourceTotal.WithLabelValues(source).Inc()
 
 	// If we got here, everything was successful
 	result.Success = true
 	result.Message = "Content extracted successfully"
 
-	w.WriteHeader(http.StatusOK)
-	enc := json.NewEncoder(w)
-	enc.SetEscapeHTML(false)
-	if err := enc.Encode(&result); err != nil {
-		log.Printf("Failed to encode response: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(Output{
-			Success: false,
-			Message: "Failed to encode response",
-		})
-		return
-	}
+	return result
 }
@@ -1,9 +1,16 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	neturl "net/url"
+	"os"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestHandleExtract(t *testing.T) {
@@ -65,3 +72,161 @@ func TestHealthCheckHandler(t *testing.T) {
 			rr.Body.String(), expected)
 	}
 }
+
+func TestHandleExtractHTML(t *testing.T) {
+	body := `<html><head><title>test</title>
+<meta property="og:image" content="/images/lead.jpg">
+<meta property="article:published_time" content="2024-01-01">
+</head><body><p>Some content</p></body></html>`
+
+	req, err := http.NewRequest("POST", "/html/?url=https://example.com/post", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(handleExtractHTML)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+
+	var result Output
+	if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+
+	if !result.Success || result.Title != "test" || result.DatePublished != "2024-01-01" {
+		t.Errorf("handler returned unexpected result: %+v", result)
+	}
+	if result.LeadImageURL != "https://example.com/images/lead.jpg" {
+		t.Errorf("handler returned unexpected lead image: got %v want %v",
+			result.LeadImageURL, "https://example.com/images/lead.jpg")
+	}
+	if result.Domain != "https://example.com" {
+		t.Errorf("handler returned unexpected domain: got %v", result.Domain)
+	}
+}
+
+func TestHandleExtractHTMLMethod(t *testing.T) {
+	req, err := http.NewRequest("GET", "/html/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(handleExtractHTML)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusMethodNotAllowed {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestExtractURLCancelled(t *testing.T) {
+	ts := newPageServer()
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest("GET", "/url/", nil).WithContext(ctx)
+
+	if result, status := extractURL(ts.URL, ExtractOptions{}, req); status != http.StatusBadGateway || result.Success {
+		t.Errorf("extractURL returned %d %+v for cancelled request", status, result)
+	}
+}
+
+func TestProbeImageRanges(t *testing.T) {
+	var mu sync.Mutex
+	ranges := make([]string, 0)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		mu.Unlock()
+		http.ServeFile(w, r, "imageutils/samples/"+strings.TrimPrefix(r.URL.Path, "/"))
+	}))
+	defer ts.Close()
+
+	var samples = []struct {
+		file   string
+		w, h   int32
+		frames int
+		ranges []string
+	}{
+		{"file.png", 521, 450, 0, []string{"bytes=0-4095"}},
+		// frame header lies behind big EXIF block
+		{"file4.jpg", 5616, 3744, 0, []string{"bytes=0-4095", "bytes=4096-20479"}},
+		{"file2.gif", 32, 24, 3, []string{"bytes=0-4095"}},
+	}
+
+	for _, sample := range samples {
+		ranges = ranges[:0]
+		result, err := probeImage(context.Background(), ts.URL+"/"+sample.file)
+		if err != nil || result.Width != sample.w || result.Height != sample.h || result.Frames != sample.frames {
+			t.Errorf("probeImage(%s) returned %+v, %v", sample.file, result, err)
+		}
+		if strings.Join(ranges, ",") != strings.Join(sample.ranges, ",") {
+			t.Errorf("probeImage(%s) requested %v, want %v", sample.file, ranges, sample.ranges)
+		}
+		if info, _ := os.Stat("imageutils/samples/" + sample.file); result.Size != info.Size() {
+			t.Errorf("probeImage(%s) size = %d, want %d", sample.file, result.Size, info.Size())
+		}
+	}
+}
+
+func TestProbeImageIgnoredRange(t *testing.T) {
+	data, err := os.ReadFile("imageutils/samples/file.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	closed := make(chan bool, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// whole file is announced, only header is sent until client goes away
+		w.Header().Set("Content-Length", "10000000")
+		w.Write(data)
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+			closed <- true
+		case <-time.After(5 * time.Second):
+			closed <- false
+		}
+	}))
+	defer ts.Close()
+
+	result, err := probeImage(context.Background(), ts.URL+"/file.png")
+	if err != nil || result.Width != 521 || result.Height != 450 || result.Size != 10000000 {
+		t.Errorf("probeImage returned %+v, %v", result, err)
+	}
+	if !<-closed {
+		t.Errorf("probeImage kept reading body after header was known")
+	}
+}
+
+func TestOptionsFromQuery(t *testing.T) {
+	tests := []struct {
+		query  string
+		images int
+		valid  bool
+	}{
+		{"", 0, true},
+		{"images=0", 0, true},
+		{"images=10", 10, true},
+		{"images=50", 50, true},
+		{"images=51", 0, false},
+		{"images=-1", 0, false},
+		{"images=x", 0, false},
+	}
+
+	for _, tt := range tests {
+		q, _ := neturl.ParseQuery(tt.query)
+		opts, err := optionsFromQuery(q)
+		if (err == nil) != tt.valid || (tt.valid && opts.Images != tt.images) {
+			t.Errorf("optionsFromQuery(%s) = %+v, %v", tt.query, opts, err)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/slav123/prom/imageutils"
+)
+
+var (
+	extractionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "prom_extractions_total",
+		Help: "Extraction requests by endpoint and outcome.",
+	}, []string{"endpoint", "outcome"})
+
+	rejectionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "prom_rejected_requests_total",
+		Help: "Requests rejected before extraction by endpoint and reason.",
+	}, []string{"endpoint", "reason"})
+
+	pageFetchSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "prom_page_fetch_duration_seconds",
+		Help:    "Time to fetch page, including reading body.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	pageBodyBytes = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "prom_page_body_bytes",
+		Help:    "Size of fetched or posted page body.",
+		Buckets: prometheus.ExponentialBuckets(1024, 4, 8),
+	})
+
+	imageProbesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "prom_image_probes_total",
+		Help: "Image probes by detected image type.",
+	}, []string{"type"})
+
+	imageProbeFailuresTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "prom_image_probe_failures_total",
+		Help: "Image probes which failed to download image header.",
+	})
+
+	imageQueueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "prom_image_queue_depth",
+		Help: "Images waiting for a worker to be probed.",
+	})
+
+	leadImageSourceTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "prom_lead_image_source_total",
+		Help: "Where lead image came from: meta, schema, twitter, scan or none.",
+	}, []string{"source"})
+)
+
+// initImageMetrics export probe counters of all registered image formats before first probe
+func initImageMetrics() {
+	for _, f := range imageutils.Formats() {
+		imageProbesTotal.WithLabelValues(f.Name)
+	}
+	imageProbesTotal.WithLabelValues("unknown")
+}
+
+// observeExtraction count finished extraction by http status
+func observeExtraction(endpoint string, status int) {
+	extractionsTotal.WithLabelValues(endpoint, outcome(status)).Inc()
+}
+
+// observeRejection count request rejected before extraction, both as failed extraction
+// and by reason like missing_url or invalid_json
+func observeRejection(endpoint string, status int, reason string) {
+	observeExtraction(endpoint, status)
+	rejectionsTotal.WithLabelValues(endpoint, reason).Inc()
+}
+
+// outcome short label for http status
+func outcome(status int) string {
+	switch status {
+	case http.StatusOK:
+		return "success"
+	case http.StatusBadRequest, http.StatusMethodNotAllowed, http.StatusRequestEntityTooLarge:
+		return "bad_request"
+	case http.StatusForbidden:
+		return "blocked"
+	case http.StatusBadGateway:
+		return "fetch_error"
+	case http.StatusUnprocessableEntity:
+		return "parse_error"
+	default:
+		return strconv.Itoa(status)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	body := `<html><head><meta property="og:image" content="https://example.com/a.jpg"></head><body></body></html>`
+	req := httptest.NewRequest("POST", "/html/", strings.NewReader(body))
+	handleExtractHTML(httptest.NewRecorder(), req)
+	initImageMetrics()
+
+	rr := httptest.NewRecorder()
+	promhttp.Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+
+	for _, expected := range []string{
+		`prom_extractions_total{endpoint="html",outcome="success"}`,
+		`prom_lead_image_source_total{source="meta"}`,
+		`prom_page_body_bytes_count`,
+		`prom_image_probes_total{type="jxl"} 0`,
+	} {
+		if !strings.Contains(rr.Body.String(), expected) {
+			t.Errorf("metrics missing %s", expected)
+		}
+	}
+}
+
+func TestMetricsRejected(t *testing.T) {
+	tests := []struct {
+		endpoint, reason string
+		handler          http.HandlerFunc
+		req              *http.Request
+	}{
+		{"url", "missing_url", handleExtract, httptest.NewRequest("GET", "/url/", nil)},
+		{"url", "invalid_options", handleExtract, httptest.NewRequest("GET", "/url/?url=https://example.com/&images=x", nil)},
+		{"html", "method", handleExtractHTML, httptest.NewRequest("GET", "/html/", nil)},
+		{"html", "missing_html", handleExtractHTML, httptest.NewRequest("POST", "/html/", strings.NewReader(" "))},
+		{"batch", "batch_size", handleBatch, httptest.NewRequest("POST", "/batch/", strings.NewReader("[]"))},
+		{"batch", "invalid_options", handleBatch, httptest.NewRequest("POST", "/batch/", strings.NewReader(`[{"url": "https://example.com/", "images": -1}]`))},
+		{"batch", "missing_url", handleBatch, httptest.NewRequest("POST", "/batch/", strings.NewReader(`[""]`))},
+		{"job", "missing_url", handleJobs, httptest.NewRequest("POST", "/jobs/", strings.NewReader(`{}`))},
+		{"job", "invalid_webhook", handleJobs, httptest.NewRequest("POST", "/jobs/", strings.NewReader(`{"url": "https://example.com/", "webhook": "ftp://x"}`))},
+	}
+
+	for _, tt := range tests {
+		counter := extractionsTotal.WithLabelValues(tt.endpoint, "bad_request")
+		rejections := rejectionsTotal.WithLabelValues(tt.endpoint, tt.reason)
+		before, rejectedBefore := testutil.ToFloat64(counter), testutil.ToFloat64(rejections)
+		tt.handler(httptest.NewRecorder(), tt.req)
+		if after := testutil.ToFloat64(counter); after != before+1 {
+			t.Errorf("%s %s counted %v bad requests, want 1", tt.req.Method, tt.req.URL, after-before)
+		}
+		if after := testutil.ToFloat64(rejections); after != rejectedBefore+1 {
+			t.Errorf("%s %s counted %v rejections with reason %s, want 1", tt.req.Method, tt.req.URL, after-rejectedBefore, tt.reason)
+		}
+	}
+}
+
+func TestOutcome(t *testing.T) {
+	tests := map[int]string{
+		http.StatusOK:                  "success",
+		http.StatusForbidden:           "blocked",
+		http.StatusBadGateway:          "fetch_error",
+		http.StatusServiceUnavailable:  "503",
+		http.StatusUnprocessableEntity: "parse_error",
+	}
+	for status, expected := range tests {
+		if result := outcome(status); result != expected {
+			t.Errorf("outcome(%d) = %v, want %v", status, result, expected)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package netguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrBlocked returned when url or address is not allowed
+var ErrBlocked = errors.New("address blocked")
+
+// blockedRanges private, loopback, link-local (including cloud metadata) and reserved networks
+var blockedRanges = []string{
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.0.0.0/24",
+	"192.0.2.0/24",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"198.51.100.0/24",
+	"203.0.113.0/24",
+	"224.0.0.0/4",
+	"240.0.0.0/4",
+	"::/128",
+	"::1/128",
+	"64:ff9b::/96",
+	"100::/64",
+	"2001:db8::/32",
+	"fc00::/7",
+	"fe80::/10",
+	"ff00::/8",
+}
+
+var blockedNets = mustParseCIDRs(blockedRanges)
+
+func mustParseCIDRs(cidrs []string) []*net.IPNet {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
+
+// Guard checks every outgoing connection, addresses are verified after DNS
+// resolution so redirects and DNS rebinding can't reach internal networks
+type Guard struct {
+	allowNets  []*net.IPNet
+	denyNets   []*net.IPNet
+	allowHosts []string
+	denyHosts  []string
+	dialer     *net.Dialer
+}
+
+// New create guard, allow and deny take IPs, CIDRs, host names or *.domain wildcards,
+// allow wins over built in blocked ranges, deny wins over everything
+func New(allow, deny []string) (*Guard, error) {
+	g := &Guard{}
+
+	var err error
+	if g.allowNets, g.allowHosts, err = parseList(allow); err != nil {
+		return nil, err
+	}
+	if g.denyNets, g.denyHosts, err = parseList(deny); err != nil {
+		return nil, err
+	}
+
+	g.dialer = &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   g.control,
+	}
+	return g, nil
+}
+
+func parseList(items []string) ([]*net.IPNet, []string, error) {
+	nets := make([]*net.IPNet, 0)
+	hosts := make([]string, 0)
+
+	for _, item := range items {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item == "" {
+			continue
+		}
+
+		if strings.Contains(item, "/") {
+			_, n, err := net.ParseCIDR(item)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid CIDR %q: %w", item, err)
+			}
+			nets = append(nets, n)
+			continue
+		}
+
+		if ip := net.ParseIP(item); ip != nil {
+			bits := 32
+			if ip.To4() == nil {
+				bits = 128
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		hosts = append(hosts, strings.TrimSuffix(item, "."))
+	}
+
+	return nets, hosts, nil
+}
+
+func matchHost(host string, patterns []string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for _, pattern := range patterns {
+		if strings.HasPrefix(pattern, "*.") {
+			if strings.HasSuffix(host, pattern[1:]) {
+				return true
+			}
+			continue
+		}
+		if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckIP verify resolved address
+func (g *Guard) CheckIP(ip net.IP) error {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+
+	if contains(g.denyNets, ip) {
+		return fmt.Errorf("%w: %s is denied", ErrBlocked, ip)
+	}
+	if contains(g.allowNets, ip) {
+		return nil
+	}
+	if contains(blockedNets, ip) {
+		return fmt.Errorf("%w: %s is private or reserved", ErrBlocked, ip)
+	}
+	return nil
+}
+
+// CheckHost verify host name against lists, IP literals are verified too
+func (g *Guard) CheckHost(host string) error {
+	if matchHost(host, g.denyHosts) {
+		return fmt.Errorf("%w: %s is denied", ErrBlocked, host)
+	}
+	if matchHost(host, g.allowHosts) {
+		return nil
+	}
+	if ip := net.ParseIP(strings.Trim(host, "[]")); ip != nil {
+		return g.CheckIP(ip)
+	}
+	return nil
+}
+
+// CheckURL verify scheme and host of url, use before request and on every redirect
+func (g *Guard) CheckURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: scheme %q is not allowed", ErrBlocked, u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("%w: missing host", ErrBlocked)
+	}
+	return g.CheckHost(u.Hostname())
+}
+
+// control runs after DNS resolution, right before connect
+func (g *Guard) control(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("%w: can't parse %s", ErrBlocked, address)
+	}
+	return g.CheckIP(ip)
+}
+
+// DialContext dial verifying resolved address, allowed host names skip address check
+func (g *Guard) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if matchHost(host, g.denyHosts) {
+		return nil, fmt.Errorf("%w: %s is denied", ErrBlocked, host)
+	}
+	if matchHost(host, g.allowHosts) {
+		d := *g.dialer
+		d.Control = nil
+		return d.DialContext(ctx, network, addr)
+	}
+
+	return g.dialer.DialContext(ctx, network, addr)
+}
+
+// CheckRedirect for http.Client, verifies every hop and stops after 10 redirects
+func (g *Guard) CheckRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	return g.CheckURL(req.URL)
+}
+
+// Client wrap transport, transport proxy is disabled so every connection is dialed by guard
+func (g *Guard) Client(tr *http.Transport, timeout time.Duration) *http.Client {
+	tr.Proxy = nil
+	tr.DialContext = g.DialContext
+	return &http.Client{
+		Timeout:       timeout,
+		Transport:     tr,
+		CheckRedirect: g.CheckRedirect,
+	}
+}
@@ -0,0 +1,92 @@
+package netguard
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestCheckURL(t *testing.T) {
+	g, err := New([]string{"10.1.2.3", "intranet.example"}, []string{"evil.example", "*.blocked.example", "8.8.8.0/24"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		url     string
+		blocked bool
+	}{
+		{"https://example.com/", false},
+		{"http://93.184.216.34/", false},
+		{"http://127.0.0.1/", true},
+		{"http://[::1]:8080/", true},
+		{"http://169.254.169.254/latest/meta-data/", true},
+		{"http://192.168.1.1/", true},
+		{"http://[::ffff:10.0.0.1]/", true},
+		{"http://10.1.2.3/", false},
+		{"http://intranet.example/", false},
+		{"http://8.8.8.8/", true},
+		{"http://evil.example/", true},
+		{"http://a.blocked.example/", true},
+		{"file:///etc/passwd", true},
+		{"gopher://example.com/", true},
+	}
+
+	for _, tt := range tests {
+		u, err := url.Parse(tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = g.CheckURL(u)
+		if blocked := errors.Is(err, ErrBlocked); blocked != tt.blocked {
+			t.Errorf("CheckURL(%s) = %v, want blocked %v", tt.url, err, tt.blocked)
+		}
+	}
+}
+
+func TestClientBlocksLoopback(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	// host name resolving to loopback is blocked on dial
+	_, port, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	g, err := New(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := g.Client(&http.Transport{}, time.Second)
+	if _, err := client.Get("http://localhost:" + port); !errors.Is(err, ErrBlocked) {
+		t.Errorf("client.Get returned %v, want ErrBlocked", err)
+	}
+
+	g, err = New([]string{"127.0.0.0/8", "::1"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = g.Client(&http.Transport{}, time.Second)
+	resp, err := client.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("client.Get with allowlist returned %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestRedirectToPrivate(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+	}))
+	defer ts.Close()
+
+	g, err := New([]string{"127.0.0.1"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := g.Client(&http.Transport{}, time.Second)
+	if _, err := client.Get(ts.URL); !errors.Is(err, ErrBlocked) {
+		t.Errorf("client.Get returned %v, want ErrBlocked", err)
+	}
+}
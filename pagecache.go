@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/slav123/prom/cache"
+)
+
+var (
+	// pageCache extraction results, nil when cache is disabled
+	pageCache cache.Store
+)
+
+// initPageCache create cache backend selected by cfg.Cache
+func initPageCache() error {
+	switch cfg.Cache.Backend {
+	case "", "none":
+		pageCache = nil
+	case "memory":
+		pageCache = cache.NewMemory(cfg.Cache.Size, cfg.Cache.MaxBytes)
+	case "disk":
+		disk, err := cache.NewDisk(cfg.Cache.Dir, cfg.Cache.Size, cfg.Cache.MaxBytes)
+		if err != nil {
+			return err
+		}
+		pageCache = disk
+	}
+
+	if pageCache != nil {
+		log.Printf("Cache: %s, ttl: %s", cfg.Cache.Backend, time.Duration(cfg.Cache.TTL))
+	}
+	return nil
+}
+
+// loadCached return cached entry and decoded output
+func loadCached(key string) (cache.Entry, Output, bool) {
+	if pageCache == nil {
+		return cache.Entry{}, Output{}, false
+	}
+
+	entry, ok := pageCache.Get(key)
+	if !ok {
+		return cache.Entry{}, Output{}, false
+	}
+
+	var output Output
+	if err := json.Unmarshal(entry.Value, &output); err != nil {
+		return cache.Entry{}, Output{}, false
+	}
+	return entry, output, true
+}
+
+// storeCached keep successful output together with validators of origin response
+func storeCached(key string, output Output, header http.Header) {
+	if pageCache == nil || !output.Success {
+		return
+	}
+
+	value, err := json.Marshal(output)
+	if err != nil {
+		return
+	}
+
+	err = pageCache.Set(cache.Entry{
+		Key:          key,
+		Value:        value,
+		ETag:         header.Get("ETag"),
+		LastModified: header.Get("Last-Modified"),
+		StoredAt:     time.Now(),
+	})
+	if err != nil {
+		log.Printf("Can't cache %s: %v", key, err)
+	}
+}
+
+// touchCached mark entry as fresh after origin confirmed it with 304
+func touchCached(entry cache.Entry) {
+	entry.StoredAt = time.Now()
+	if err := pageCache.Set(entry); err != nil {
+		log.Printf("Can't cache %s: %v", entry.Key, err)
+	}
+}
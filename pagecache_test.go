@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/slav123/prom/cache"
+	"github.com/slav123/prom/config"
+)
+
+func TestExtractURLCache(t *testing.T) {
+	var requests, revalidated int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&revalidated, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		fmt.Fprint(w, `<html><head><title>cached</title>
+<meta property="og:image" content="https://example.com/lead.jpg"></head><body></body></html>`)
+	}))
+	defer ts.Close()
+
+	pageCache = cache.NewMemory(10, 0)
+	defer func() {
+		pageCache = nil
+		cfg = config.Default()
+	}()
+
+	req := httptest.NewRequest("GET", "/url/", nil)
+
+	for i := 0; i < 2; i++ {
+		result, status := extractURL(ts.URL+"/page", ExtractOptions{}, req)
+		if status != http.StatusOK || result.Title != "cached" {
+			t.Fatalf("extractURL returned %d %+v", status, result)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("origin got %d requests, want 1 for fresh entry", requests)
+	}
+
+	// stale entry is revalidated with ETag
+	cfg.Cache.TTL = 0
+	result, status := extractURL(ts.URL+"/page", ExtractOptions{}, req)
+	if status != http.StatusOK || result.Title != "cached" {
+		t.Fatalf("extractURL returned %d %+v", status, result)
+	}
+	if requests != 2 || revalidated != 1 {
+		t.Errorf("origin got %d requests and %d revalidations, want 2 and 1", requests, revalidated)
+	}
+}
+
+func TestExtractURLCacheBlocked(t *testing.T) {
+	url := "http://10.0.0.1/page"
+	pageCache = cache.NewMemory(10, 0)
+	defer func() { pageCache = nil }()
+	storeCached(cache.NormalizeURL(url), Output{Success: true, Title: "cached"}, http.Header{})
+
+	req := httptest.NewRequest("GET", "/url/", nil)
+	result, status := extractURL(url, ExtractOptions{}, req)
+	if status != http.StatusForbidden || result.Success {
+		t.Errorf("extractURL(%s) returned %d %+v, want %d", url, status, result, http.StatusForbidden)
+	}
+}
+
+func TestExtractURLCacheLanguage(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"`+r.Header.Get("Accept-Language")+`"`)
+		fmt.Fprintf(w, `<html><head><title>%s</title></head><body></body></html>`, r.Header.Get("Accept-Language"))
+	}))
+	defer ts.Close()
+
+	pageCache = cache.NewMemory(10, 0)
+	defer func() { pageCache = nil }()
+
+	// each language variant is cached on its own
+	for i := 0; i < 2; i++ {
+		for _, language := range []string{"pl", "de"} {
+			req := httptest.NewRequest("GET", "/url/", nil)
+			req.Header.Set("Accept-Language", language)
+			result, status := extractURL(ts.URL+"/page", ExtractOptions{}, req)
+			if status != http.StatusOK || result.Title != language {
+				t.Errorf("extractURL with Accept-Language %s returned %d %+v", language, status, result)
+			}
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/slav123/prom/config"
+	"github.com/slav123/prom/htmlutils"
+)
+
+// ImageScore score of lead image candidate with contribution of every factor,
+// weights come from cfg.Scoring
+type ImageScore struct {
+	Total    float64            `json:"total"`
+	Eligible bool               `json:"eligible"`
+	Factors  map[string]float64 `json:"factors"`
+}
+
+// ScoredImage probed lead image candidate, Source tells where it was found:
+// og:image, twitter:image, json-ld, meta or img
+type ScoredImage struct {
+	ImageResult
+	Source    string     `json:"source"`
+	Alt       string     `json:"alt,omitempty"`
+	Position  int        `json:"-"` // order in document, -1 for meta candidates
+	InContent bool       `json:"in_content"`
+	Score     ImageScore `json:"score"`
+}
+
+// metaCandidates images declared by site: og:image, meta image, JSON-LD and twitter:image
+func metaCandidates(result *Output, metaImage, schemaImage, baseURL string) []ScoredImage {
+	candidates := make([]ScoredImage, 0)
+	add := func(src, source, alt string) {
+		if src == "" {
+			return
+		}
+		candidate := ScoredImage{Source: source, Alt: alt}
+		candidate.URL = htmlutils.GetBaseUrlString(src, baseURL)
+		candidates = append(candidates, candidate)
+	}
+
+	if result.OpenGraph != nil {
+		for _, image := range result.OpenGraph.Images {
+			src := image.URL
+			if image.SecureURL != "" {
+				src = image.SecureURL
+			}
+			add(src, "og:image", image.Alt)
+		}
+	}
+	add(metaImage, "meta", "")
+	add(schemaImage, "json-ld", "")
+	if result.Twitter != nil {
+		add(result.Twitter.Image, "twitter:image", result.Twitter.ImageAlt)
+	}
+
+	return candidates
+}
+
+// scoreImage rate candidate, images below minimum size are not eligible at all
+func scoreImage(image ScoredImage, hints string, count int, s config.Scoring) ImageScore {
+	score := ImageScore{
+		Factors: make(map[string]float64),
+	}
+
+	// vector image can be rendered at any size, only its aspect ratio matters
+	scalable := s.VectorScalable && image.Vector && image.Area > 0
+
+	score.Eligible = image.Area > 0 &&
+		(scalable || int(image.Width) >= s.MinWidth && int(image.Height) >= s.MinHeight) &&
+		!(s.SkipAnimated && image.Animated)
+
+	// bigger is better, up to target area
+	if image.Area > 0 {
+		area := float64(image.Area) / float64(s.AreaTarget)
+		if area > 1 || scalable {
+			area = 1
+		}
+		score.Factors["area"] = area * s.AreaWeight
+	}
+
+	// banners, dividers and skyscrapers
+	if image.Width > 0 && image.Height > 0 {
+		aspect := float64(image.Width) / float64(image.Height)
+		if aspect < s.MinAspect || aspect > s.MaxAspect {
+			score.Factors["aspect"] = -s.AspectPenalty
+		}
+	}
+
+	// images closer to top of document are more likely to be lead,
+	// images picked by site for previews are even better
+	if image.Position < 0 {
+		score.Factors["source"] = s.MetaWeight
+	} else if count > 0 {
+		score.Factors["position"] = s.PositionWeight * (1 - float64(image.Position)/float64(count))
+	}
+
+	if image.InContent {
+		score.Factors["content"] = s.ContentWeight
+	}
+
+	if image.Alt != "" {
+		score.Factors["alt"] = s.AltWeight
+	}
+
+	// spinners, reactions and ads, a still hero image makes better preview
+	if image.Animated {
+		score.Factors["animation"] = -s.AnimationPenalty
+	}
+
+	if hasNegativeHint(hints, s.NegativeHints) {
+		score.Factors["hints"] = -s.HintPenalty
+	}
+
+	// fixed order, float sum of map in random order may differ in last bits and break ties
+	keys := make([]string, 0, len(score.Factors))
+	for key := range score.Factors {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		score.Total += score.Factors[key]
+	}
+
+	return score
+}
+
+// hasNegativeHint hints must match whole word or its plural, longer ones may also end
+// compound word (sitelogo), words are split on punctuation and camelCase
+func hasNegativeHint(hints string, negative []string) bool {
+	words := hintWords(hints)
+
+	for _, hint := range negative {
+		hint = strings.ToLower(hint)
+		for _, word := range words {
+			if word == hint || word == hint+"s" || (len(hint) > 3 && strings.HasSuffix(word, hint)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hintWords lower case words of class names, ids and file names, "site-logo_2",
+// "siteLogo" and "SiteLOGO" give site and logo
+func hintWords(hints string) []string {
+	words := make([]string, 0)
+	for _, field := range strings.FieldsFunc(hints, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		start := 0
+		runes := []rune(field)
+		for i := 1; i < len(runes); i++ {
+			if unicode.IsUpper(runes[i]) && unicode.IsLower(runes[i-1]) {
+				words = append(words, strings.ToLower(string(runes[start:i])))
+				start = i
+			}
+		}
+		words = append(words, strings.ToLower(string(runes[start:])))
+	}
+	return words
+}
+
+// rankImages sort candidates, eligible first, then by score, then by document order
+func rankImages(candidates []ScoredImage) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.Score.Eligible != b.Score.Eligible {
+			return a.Score.Eligible
+		}
+		if a.Score.Total != b.Score.Total {
+			return a.Score.Total > b.Score.Total
+		}
+		return a.Position < b.Position
+	})
+}
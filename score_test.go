@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/slav123/prom/config"
+)
+
+func TestScoreImage(t *testing.T) {
+	s := config.Default().Scoring
+
+	image := func(url string, w, h int32, pos int) ScoredImage {
+		return ScoredImage{
+			ImageResult: ImageResult{URL: url, Width: w, Height: h, Area: int(w * h)},
+			Position:    pos,
+		}
+	}
+
+	// huge divider at top, logo, tiny tracking pixel and photo inside article
+	divider := image("divider.png", 3000, 50, 0)
+	logo := image("logo.png", 400, 400, 1)
+	pixel := image("p.gif", 1, 1, 2)
+	photo := image("photo.jpg", 800, 500, 3)
+	photo.InContent = true
+	photo.Alt = "photo"
+
+	candidates := []ScoredImage{divider, logo, pixel, photo}
+	hints := []string{"divider.png", "site-logo logo.png", "p.gif", "article-body photo.jpg"}
+	for i := range candidates {
+		candidates[i].Score = scoreImage(candidates[i], hints[i], len(candidates), s)
+	}
+	rankImages(candidates)
+
+	if candidates[0].URL != "photo.jpg" {
+		t.Errorf("rankImages picked %s, want photo.jpg", candidates[0].URL)
+	}
+
+	for _, candidate := range candidates {
+		switch candidate.URL {
+		case "p.gif":
+			if candidate.Score.Eligible {
+				t.Errorf("tiny image is eligible: %+v", candidate.Score)
+			}
+		case "divider.png":
+			if candidate.Score.Factors["aspect"] >= 0 {
+				t.Errorf("divider has no aspect penalty: %+v", candidate.Score)
+			}
+		case "logo.png":
+			if candidate.Score.Factors["hints"] >= 0 {
+				t.Errorf("logo has no hint penalty: %+v", candidate.Score)
+			}
+		}
+	}
+}
+
+func TestScoreImageVector(t *testing.T) {
+	s := config.Default().Scoring
+
+	// chart drawn in 60x40 viewBox
+	chart := ScoredImage{ImageResult: ImageResult{URL: "chart.svg", Width: 60, Height: 40, Area: 2400, Vector: true}}
+	if score := scoreImage(chart, "", 1, s); score.Eligible {
+		t.Errorf("small vector image is eligible by default: %+v", score)
+	}
+
+	s.VectorScalable = true
+	score := scoreImage(chart, "", 1, s)
+	if !score.Eligible || score.Factors["area"] != s.AreaWeight {
+		t.Errorf("scalable vector image score = %+v", score)
+	}
+
+	// no intrinsic size, nothing to scale
+	chart.Width, chart.Height, chart.Area = 0, 0, 0
+	if score := scoreImage(chart, "", 1, s); score.Eligible {
+		t.Errorf("vector image without size is eligible: %+v", score)
+	}
+}
+
+func TestScoreImageAnimated(t *testing.T) {
+	s := config.Default().Scoring
+
+	spinner := ScoredImage{ImageResult: ImageResult{URL: "loading.gif", Width: 400, Height: 300, Area: 120000, Animated: true, Frames: 12}}
+	hero := ScoredImage{ImageResult: ImageResult{URL: "hero.jpg", Width: 400, Height: 300, Area: 120000}}
+
+	animated, still := scoreImage(spinner, "", 1, s), scoreImage(hero, "", 1, s)
+	if !animated.Eligible || animated.Factors["animation"] != -s.AnimationPenalty || animated.Total >= still.Total {
+		t.Errorf("animated image score = %+v, still image score = %+v", animated, still)
+	}
+
+	s.SkipAnimated = true
+	if score := scoreImage(spinner, "", 1, s); score.Eligible {
+		t.Errorf("animated image is eligible with skip_animated: %+v", score)
+	}
+}
+
+func TestHasNegativeHint(t *testing.T) {
+	negative := config.Default().Scoring.NegativeHints
+
+	tests := map[string]bool{
+		"header-image upload.jpg": false,
+		"ad-slot":                 true,
+		"sitelogo":                true,
+		"user avatar-32":          true,
+		"hero lead":               false,
+		"siteLogo":                true,
+		"site_logo":               true,
+		"HeaderBanner.png":        true,
+		"share-icons":             true,
+		"honey-badgers.jpg":       false,
+		"iconic-view":             false,
+		"bannerman":               false,
+		"adventure header":        false,
+		"roadmap":                 false,
+	}
+	for hints, expected := range tests {
+		if result := hasNegativeHint(hints, negative); result != expected {
+			t.Errorf("hasNegativeHint(%q) = %v, want %v", hints, result, expected)
+		}
+	}
+}
+
+func TestExtractHTMLImages(t *testing.T) {
+	ts := httptest.NewServer(http.FileServer(http.Dir("imageutils/samples")))
+	defer ts.Close()
+
+	body := fmt.Sprintf(`<html><head>
+<meta property="og:image" content="%s/file2.jpg"><meta property="og:image:alt" content="og alt">
+</head><body>
+<img src="/file.gif" class="site-logo"><p><img src="/file3.jpg" alt="photo"></p><img src="/file2.jpg">
+</body></html>`, ts.URL)
+
+	req := httptest.NewRequest("POST", "/html/?images=2&url="+ts.URL+"/post", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	handleExtractHTML(rr, req)
+
+	var result Output
+	if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Images) != 2 {
+		t.Fatalf("handler returned %d images, want 2: %+v", len(result.Images), result.Images)
+	}
+
+	first := result.Images[0]
+	if first.URL != ts.URL+"/file2.jpg" || first.Source != "og:image" || first.Alt != "og alt" ||
+		first.Type != "jpg" || first.Width != 550 || first.Height != 449 || first.Size != 97358 {
+		t.Errorf("handler returned unexpected first image: %+v", first)
+	}
+	if second := result.Images[1]; second.URL != ts.URL+"/file3.jpg" || second.Source != "img" {
+		t.Errorf("handler returned unexpected second image: %+v", second)
+	}
+	if result.LeadImageURL != ts.URL+"/file2.jpg" {
+		t.Errorf("handler returned unexpected lead image: %v", result.LeadImageURL)
+	}
+}
+
+func TestExtractHTMLImagesFailedProbe(t *testing.T) {
+	ts := httptest.NewServer(http.FileServer(http.Dir("imageutils/samples")))
+	defer ts.Close()
+
+	body := `<html><head></head><body>
+<img src="/missing.jpg" width="800" height="600"><img src="/icon.png" width="16" height="16">
+</body></html>`
+
+	req := httptest.NewRequest("POST", "/html/?images=2&url="+ts.URL+"/post", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	handleExtractHTML(rr, req)
+
+	var result Output
+	if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+
+	sizes := make(map[string][2]int32)
+	for _, image := range result.Images {
+		sizes[image.URL] = [2]int32{image.Width, image.Height}
+	}
+	// 404 doesn't get size from markup, skipped probe does
+	if size := sizes[ts.URL+"/missing.jpg"]; size != [2]int32{0, 0} {
+		t.Errorf("image with failed probe has size %v, want 0x0", size)
+	}
+	if size := sizes[ts.URL+"/icon.png"]; size != [2]int32{16, 16} {
+		t.Errorf("image not probed has size %v, want declared 16x16", size)
+	}
+	if result.LeadImageURL != "" {
+		t.Errorf("handler picked lead image %v of failed probe", result.LeadImageURL)
+	}
+}
+
+func TestScoreImageTotalStable(t *testing.T) {
+	s := config.Default().Scoring
+	s.AltWeight, s.ContentWeight = 0.1, 0.7
+
+	image := ScoredImage{ImageResult: ImageResult{Width: 640, Height: 100, Area: 64000, Animated: true}, Position: 3, InContent: true, Alt: "x"}
+	first := scoreImage(image, "site-logo", 7, s).Total
+	for i := 0; i < 50; i++ {
+		if total := scoreImage(image, "site-logo", 7, s).Total; total != first {
+			t.Fatalf("scoreImage total %v differs from %v", total, first)
+		}
+	}
+}